@@ -3,6 +3,8 @@ package auth
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/appootb/substratum/v2/errors"
@@ -11,36 +13,75 @@ import (
 	"github.com/appootb/substratum/v2/proto/go/permission"
 	"github.com/appootb/substratum/v2/proto/go/secret"
 	"github.com/appootb/substratum/v2/service"
+	"github.com/appootb/substratum/v2/token"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// TokenParser parses the token of the request metadata,
+// parsers of different token kinds could be chained by ChainTokenParser.
 type TokenParser interface {
 	// Parse the token string.
 	Parse(md *common.Metadata) (*secret.Info, error)
 }
 
-func NewAlgorithmAuth(client, server TokenParser) service.Authenticator {
-	return &AlgorithmAuth{
+type AlgorithmAuthOption func(*AlgorithmAuth)
+
+// WithAPIKeyParser sets the parser of the tokens signed by API keys, prefixed with token.APIKeyScheme.
+func WithAPIKeyParser(parser TokenParser) AlgorithmAuthOption {
+	return func(n *AlgorithmAuth) {
+		n.apiKeyTokenParser = parser
+	}
+}
+
+// WithPolicyEvaluator sets the policy evaluator of the method roles, defaults to an empty RBAC.
+func WithPolicyEvaluator(evaluator PolicyEvaluator) AlgorithmAuthOption {
+	return func(n *AlgorithmAuth) {
+		n.policyEvaluator = evaluator
+	}
+}
+
+// WithMethodOverrides sets the overrides of the method subjects and roles, which are applied while serving.
+func WithMethodOverrides(overrides *MethodOverrides) AlgorithmAuthOption {
+	return func(n *AlgorithmAuth) {
+		n.methodOverrides = overrides
+	}
+}
+
+func NewAlgorithmAuth(client, server TokenParser, opts ...AlgorithmAuthOption) service.Authenticator {
+	n := &AlgorithmAuth{
 		clientTokenParser: client,
 		serverTokenParser: server,
+		policyEvaluator:   NewRBAC(),
+		methodOverrides:   NewMethodOverrides(),
 		methodComponent:   make(map[string]string),
 		methodSubjects:    make(map[string][]permission.Subject),
 		methodRoles:       make(map[string][]string),
 	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	return n
 }
 
 type AlgorithmAuth struct {
 	clientTokenParser TokenParser
 	serverTokenParser TokenParser
-	methodComponent   map[string]string
-	methodSubjects    map[string][]permission.Subject
-	methodRoles       map[string][]string
+	apiKeyTokenParser TokenParser
+	policyEvaluator   PolicyEvaluator
+	methodOverrides   *MethodOverrides
+
+	mu              sync.RWMutex
+	methodComponent map[string]string
+	methodSubjects  map[string][]permission.Subject
+	methodRoles     map[string][]string
 }
 
 // ServiceComponentName returns the component name implements the service method.
 func (n *AlgorithmAuth) ServiceComponentName(serviceMethod string) string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
 	return n.methodComponent[serviceMethod]
 }
 
@@ -50,6 +91,8 @@ func (n *AlgorithmAuth) RegisterServiceSubjects(component string,
 	serviceMethodSubjects map[string][]permission.Subject,
 	serviceMethodRoles map[string][]string) {
 	//
+	n.mu.Lock()
+	defer n.mu.Unlock()
 	for methodURL, methodSubjects := range serviceMethodSubjects {
 		n.methodComponent[methodURL] = component
 		n.methodSubjects[methodURL] = methodSubjects
@@ -60,70 +103,126 @@ func (n *AlgorithmAuth) RegisterServiceSubjects(component string,
 	}
 }
 
-// Authenticate a request specified by the full url path of the method.
+// methodPermission returns the required subjects and roles of the method, overridden if configured.
+func (n *AlgorithmAuth) methodPermission(serviceMethod string) ([]permission.Subject, []string, *MethodOverride) {
+	n.mu.RLock()
+	subjects, roles := n.methodSubjects[serviceMethod], n.methodRoles[serviceMethod]
+	n.mu.RUnlock()
+	override := n.methodOverrides.Get(serviceMethod)
+	if override == nil {
+		return subjects, roles, nil
+	}
+	if override.subjects != nil {
+		subjects = override.subjects
+	}
+	if override.Roles != nil {
+		roles = override.Roles
+	}
+	return subjects, roles, override
+}
+
+// Authenticate a request specified by the full url path of the method,
+// the decision is recorded to the audit log and metrics.
 func (n *AlgorithmAuth) Authenticate(ctx context.Context, serviceMethod string) (*secret.Info, error) {
+	md := metadata.IncomingMetadata(ctx)
+	secretInfo, decision, reason, err := n.authenticate(md, serviceMethod)
+	audit(md, serviceMethod, secretInfo, decision, reason, err)
+	if err != nil {
+		return nil, err
+	}
+	return secretInfo, nil
+}
+
+// authenticate returns the decision and its reason, the parsed secret info is returned for auditing on denial.
+func (n *AlgorithmAuth) authenticate(md *common.Metadata, serviceMethod string) (*secret.Info, string, string, error) {
 	dt := time.Now().Add(-time.Minute)
-	anonymousMethod := n.IsAnonymousMethod(serviceMethod)
+	subjects, roles, override := n.methodPermission(serviceMethod)
+	anonymousMethod := isAnonymous(subjects)
 	emptySecret := &secret.Info{
 		Roles:     []string{},
 		IssuedAt:  timestamppb.New(dt),
 		ExpiredAt: timestamppb.New(dt),
 	}
 
+	// Method overrides.
+	if override != nil {
+		if override.Maintenance {
+			msg := override.Message
+			if msg == "" {
+				msg = "substratum: method under maintenance"
+			}
+			return nil, DecisionDeny, "method under maintenance", status.Error(codes.Unavailable, msg)
+		}
+		if override.Disabled {
+			return nil, DecisionDeny, "method disabled", status.Error(codes.PermissionDenied, "substratum: method disabled")
+		}
+	}
+
 	// Get request metadata.
-	md := metadata.IncomingMetadata(ctx)
 	if md == nil {
-		return nil, status.Error(codes.Unauthenticated, "request metadata not set")
+		return nil, DecisionDeny, "metadata not set", status.Error(codes.Unauthenticated, "request metadata not set")
 	}
 	if md.GetToken() == "" {
 		if anonymousMethod {
-			return emptySecret, nil
+			return emptySecret, DecisionAnonymous, "token not set", nil
 		}
-		return nil, status.Error(codes.Unauthenticated, "token required")
+		return nil, DecisionDeny, "token not set", status.Error(codes.Unauthenticated, "token required")
 	}
 
 	// Parse the token.
 	var (
 		err        error
 		secretInfo *secret.Info
+		apiKey     = n.apiKeyTokenParser != nil && strings.HasPrefix(md.GetToken(), token.APIKeyScheme)
 	)
-	if md.GetPlatform()&common.Platform_PLATFORM_SERVER == common.Platform_PLATFORM_SERVER {
+	if apiKey {
+		secretInfo, err = n.apiKeyTokenParser.Parse(md)
+	} else if md.GetPlatform()&common.Platform_PLATFORM_SERVER == common.Platform_PLATFORM_SERVER {
 		secretInfo, err = n.serverTokenParser.Parse(md)
 	} else {
 		secretInfo, err = n.clientTokenParser.Parse(md)
 	}
 	if err != nil {
 		if anonymousMethod {
-			return emptySecret, nil
+			return emptySecret, DecisionAnonymous, err.Error(), nil
 		}
 		switch errors.ErrorCode(err) {
 		case int32(codes.AlreadyExists),
 			int32(codes.FailedPrecondition),
 			int32(codes.Unauthenticated):
-			return nil, err
+			return nil, DecisionDeny, err.Error(), err
 		default:
-			return nil, status.Error(codes.Unauthenticated, "verify token failed")
+			return nil, DecisionDeny, err.Error(), status.Error(codes.Unauthenticated, "verify token failed")
 		}
 	}
 	// Anonymous method
 	if anonymousMethod {
-		return secretInfo, nil
+		return secretInfo, DecisionAnonymous, "anonymous method", nil
 	}
-	// Verify the subject.
-	if !n.IsValidPlatform(secretInfo.GetSubject(), md.GetPlatform()) {
-		return nil, status.Error(codes.Unauthenticated, "invalid token usage")
+	// Verify the subject, API keys are bound to the subject regardless of the platform.
+	if !apiKey && !n.IsValidPlatform(secretInfo.GetSubject(), md.GetPlatform()) {
+		return secretInfo, DecisionDeny, "subject not allowed on the platform",
+			status.Error(codes.Unauthenticated, "invalid token usage")
 	}
-	for _, sub := range n.methodSubjects[serviceMethod] {
+	for _, sub := range subjects {
 		if (sub & secretInfo.GetSubject()) == sub {
-			return n.CheckPolicy(serviceMethod, secretInfo)
+			if err = n.evaluate(serviceMethod, roles, override, secretInfo); err != nil {
+				return secretInfo, DecisionDeny, err.Error(), err
+			}
+			return secretInfo, DecisionAllow, "", nil
 		}
 	}
-	return nil, status.Error(codes.PermissionDenied,
-		fmt.Sprintf("subject: %v, expeced: %v", secretInfo.GetSubject(), n.methodSubjects[serviceMethod]))
+	return secretInfo, DecisionDeny, "subject not allowed on the method", status.Error(codes.PermissionDenied,
+		fmt.Sprintf("subject: %v, expeced: %v", secretInfo.GetSubject(), subjects))
 }
 
 func (n *AlgorithmAuth) IsAnonymousMethod(serviceMethod string) bool {
-	for _, aud := range n.methodSubjects[serviceMethod] {
+	subjects, _, _ := n.methodPermission(serviceMethod)
+	return isAnonymous(subjects)
+}
+
+func isAnonymous(subjects []permission.Subject) bool {
+	for _, aud := range subjects {
 		if aud == permission.Subject_NONE {
 			return true
 		}
@@ -149,23 +248,22 @@ func (n *AlgorithmAuth) IsValidPlatform(sub permission.Subject, platform common.
 }
 
 func (n *AlgorithmAuth) CheckPolicy(serviceMethod string, secretInfo *secret.Info) (*secret.Info, error) {
-	// Check roles.
-	methodRoles, ok := n.methodRoles[serviceMethod]
-	if !ok {
-		return secretInfo, nil
-	}
-	secretRoles := n.GetSecretRoles(secretInfo)
-	// Admin role.
-	if _, ok = secretRoles["admin"]; ok {
-		return secretInfo, nil
-	}
-	for _, role := range methodRoles {
-		if _, ok = secretRoles[role]; ok {
-			return secretInfo, nil
+	_, roles, override := n.methodPermission(serviceMethod)
+	if err := n.evaluate(serviceMethod, roles, override, secretInfo); err != nil {
+		return nil, err
+	}
+	return secretInfo, nil
+}
+
+// evaluate the policy of the method. The roles of the method override take precedence over
+// the policy of the evaluator if it implements RoleEvaluator.
+func (n *AlgorithmAuth) evaluate(serviceMethod string, roles []string, override *MethodOverride, secretInfo *secret.Info) error {
+	if override != nil && override.Roles != nil {
+		if evaluator, ok := n.policyEvaluator.(RoleEvaluator); ok {
+			return evaluator.EvaluateRoles(override.Roles, secretInfo)
 		}
 	}
-	return nil, status.Error(codes.PermissionDenied,
-		fmt.Sprintf("roles: %v, expeced roles: %v", secretInfo.GetRoles(), methodRoles))
+	return n.policyEvaluator.Evaluate(serviceMethod, roles, secretInfo)
 }
 
 func (n *AlgorithmAuth) GetSecretRoles(secretInfo *secret.Info) map[string]bool {
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"github.com/appootb/substratum/v2/errors"
+	"github.com/appootb/substratum/v2/logger"
+	"github.com/appootb/substratum/v2/proto/go/common"
+	"github.com/appootb/substratum/v2/proto/go/secret"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Authentication decisions.
+const (
+	DecisionAllow     = "allow"
+	DecisionAnonymous = "anonymous"
+	DecisionDeny      = "deny"
+)
+
+var (
+	authDecisions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "substratum",
+		Subsystem: "auth",
+		Name:      "decisions_total",
+		Help:      "Total number of authentication decisions, by method and decision.",
+	}, []string{"method", "decision"})
+)
+
+func init() {
+	prometheus.MustRegister(authDecisions)
+}
+
+// audit emits the authentication decision to the audit log channel and the metrics,
+// denials are logged at warning level.
+func audit(md *common.Metadata, serviceMethod string, secretInfo *secret.Info, decision, reason string, err error) {
+	authDecisions.WithLabelValues(serviceMethod, decision).Inc()
+	if logger.Implementor() == nil {
+		return
+	}
+	level := logger.InfoLevel
+	content := logger.Content{
+		logger.LogPath: serviceMethod,
+		"account":      secretInfo.GetAccount(),
+		"key_id":       secretInfo.GetKeyId(),
+		"subject":      secretInfo.GetSubject().String(),
+		"token_type":   secretInfo.GetType().String(),
+		"platform":     md.GetPlatform().String(),
+		"client_ip":    md.GetClientIp(),
+		"trace_id":     md.GetTraceId(),
+		"decision":     decision,
+		"reason":       reason,
+	}
+	if err != nil {
+		level = logger.WarnLevel
+		content["code"] = errors.ErrorCode(err)
+	}
+	logger.Implementor().Log(level, nil, logger.AuditLog, content)
+}
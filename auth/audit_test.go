@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/appootb/substratum/v2/metadata"
+	"github.com/appootb/substratum/v2/proto/go/common"
+	"github.com/appootb/substratum/v2/proto/go/permission"
+	"github.com/appootb/substratum/v2/proto/go/secret"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc/codes"
+	grpcmd "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type tokenParserFunc func(md *common.Metadata) (*secret.Info, error)
+
+func (fn tokenParserFunc) Parse(md *common.Metadata) (*secret.Info, error) {
+	return fn(md)
+}
+
+func TestAlgorithmAuth_Audit(t *testing.T) {
+	parser := tokenParserFunc(func(md *common.Metadata) (*secret.Info, error) {
+		if md.GetToken() != "valid" {
+			return nil, status.Error(codes.Unauthenticated, "bad token")
+		}
+		return &secret.Info{Account: 1001, Subject: permission.Subject_MOBILE}, nil
+	})
+	method := "/test.Service/Audit"
+	n := NewAlgorithmAuth(parser, parser).(*AlgorithmAuth)
+	n.RegisterServiceSubjects("test", map[string][]permission.Subject{
+		method: {permission.Subject_MOBILE},
+	}, nil)
+
+	authenticate := func(token string, platform common.Platform) error {
+		ctx := metadata.ContextWithIncomingMetadata(grpcmd.NewIncomingContext(context.Background(),
+			grpcmd.Pairs(metadata.KeyToken, token, metadata.KeyPlatform, strconv.Itoa(int(platform)))))
+		_, err := n.Authenticate(ctx, method)
+		return err
+	}
+	if err := authenticate("valid", common.Platform_PLATFORM_IOS); err != nil {
+		t.Fatal(err)
+	}
+	if err := authenticate("invalid", common.Platform_PLATFORM_IOS); err == nil {
+		t.Fatal("invalid token permitted")
+	}
+	if err := authenticate("valid", common.Platform_PLATFORM_WEB); err == nil {
+		t.Fatal("invalid token usage permitted")
+	}
+	if v := testutil.ToFloat64(authDecisions.WithLabelValues(method, DecisionAllow)); v != 1 {
+		t.Fatal("unexpected allow decisions", v)
+	}
+	if v := testutil.ToFloat64(authDecisions.WithLabelValues(method, DecisionDeny)); v != 2 {
+		t.Fatal("unexpected deny decisions", v)
+	}
+}
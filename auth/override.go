@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/appootb/substratum/v2/configure"
+	ictx "github.com/appootb/substratum/v2/internal/context"
+	"github.com/appootb/substratum/v2/logger"
+	"github.com/appootb/substratum/v2/proto/go/permission"
+)
+
+// MethodOverridePrefix is the configure backend directory of the method overrides,
+// the key of a method is the prefix followed by its full url path without the leading slash,
+// e.g. `config/_auth/appootb.example.Service/Method`.
+const MethodOverridePrefix = "config/_auth/"
+
+// MethodOverride overrides the subjects and roles declared by the method options.
+type MethodOverride struct {
+	// Subjects replaces the required subjects if set, e.g. `["NONE"]` or `["MOBILE", "SERVER"]`.
+	Subjects []string `json:"subjects,omitempty"`
+	// Roles replaces the required roles if set, an empty array requires no roles.
+	// The roles take precedence over the methods of the RBAC policy, which take precedence over the method options.
+	Roles []string `json:"roles,omitempty"`
+	// Disabled rejects all calls with codes.PermissionDenied.
+	Disabled bool `json:"disabled,omitempty"`
+	// Maintenance rejects all calls with codes.Unavailable and the message.
+	Maintenance bool   `json:"maintenance,omitempty"`
+	Message     string `json:"message,omitempty"`
+
+	subjects []permission.Subject
+}
+
+func (o *MethodOverride) compile() error {
+	if o.Subjects == nil {
+		return nil
+	}
+	o.subjects = make([]permission.Subject, 0, len(o.Subjects))
+	for _, name := range o.Subjects {
+		sub, ok := permission.Subject_value[strings.ToUpper(name)]
+		if !ok {
+			return fmt.Errorf("unknown subject: %s", name)
+		}
+		o.subjects = append(o.subjects, permission.Subject(sub))
+	}
+	return nil
+}
+
+// MethodOverrides holds the method overrides by the full url path of the method,
+// which are swapped atomically on changes.
+type MethodOverrides struct {
+	mu        sync.Mutex
+	overrides atomic.Value
+}
+
+func NewMethodOverrides() *MethodOverrides {
+	o := &MethodOverrides{}
+	o.overrides.Store(map[string]*MethodOverride{})
+	return o
+}
+
+// Get the override of the method, nil if not overridden.
+func (o *MethodOverrides) Get(serviceMethod string) *MethodOverride {
+	return o.overrides.Load().(map[string]*MethodOverride)[serviceMethod]
+}
+
+// Update replaces all the overrides atomically.
+func (o *MethodOverrides) Update(overrides map[string]*MethodOverride) error {
+	m := make(map[string]*MethodOverride, len(overrides))
+	for serviceMethod, override := range overrides {
+		if err := override.compile(); err != nil {
+			return err
+		}
+		m[serviceMethod] = override
+	}
+	o.mu.Lock()
+	o.overrides.Store(m)
+	o.mu.Unlock()
+	return nil
+}
+
+// Set the override of the method, nil removes the override.
+func (o *MethodOverrides) Set(serviceMethod string, override *MethodOverride) error {
+	if override != nil {
+		if err := override.compile(); err != nil {
+			return err
+		}
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	old := o.overrides.Load().(map[string]*MethodOverride)
+	m := make(map[string]*MethodOverride, len(old)+1)
+	for k, v := range old {
+		m[k] = v
+	}
+	if override == nil {
+		delete(m, serviceMethod)
+	} else {
+		m[serviceMethod] = override
+	}
+	o.overrides.Store(m)
+	return nil
+}
+
+// Watch loads the overrides from the configure backend directory, and reloads them on changes.
+func (o *MethodOverrides) Watch(prefix string) error {
+	backend := configure.BackendImplementor()
+	pairs, err := o.load(prefix)
+	if err != nil {
+		return err
+	}
+	evtChan, err := backend.Watch(prefix, pairs.Version, true)
+	if err != nil {
+		return err
+	}
+	go o.watchEvent(prefix, evtChan)
+	return nil
+}
+
+func (o *MethodOverrides) load(prefix string) (*configure.KVPairs, error) {
+	pairs, err := configure.BackendImplementor().Get(prefix, true)
+	if err != nil {
+		return nil, err
+	}
+	// Invalid overrides are skipped.
+	overrides := make(map[string]*MethodOverride, len(pairs.KVs))
+	for _, kv := range pairs.KVs {
+		override, err := parseMethodOverride(kv.Value)
+		if err == nil && override != nil {
+			err = override.compile()
+		}
+		if err != nil {
+			logger.Error("substratum method override invalid", logger.Content{
+				"error": err.Error(),
+				"key":   kv.Key,
+			})
+			continue
+		}
+		if override != nil {
+			overrides[overrideMethod(prefix, kv.Key)] = override
+		}
+	}
+	return pairs, o.Update(overrides)
+}
+
+func (o *MethodOverrides) watchEvent(prefix string, ch configure.EventChan) {
+	for {
+		select {
+		case <-ictx.Context.Done():
+			return
+
+		case evt := <-ch:
+			var (
+				err      error
+				override *MethodOverride
+			)
+			switch evt.EventType {
+			case configure.Delete:
+				err = o.Set(overrideMethod(prefix, evt.Key), nil)
+			case configure.Refresh:
+				_, err = o.load(prefix)
+			default:
+				if override, err = parseMethodOverride(evt.Value); err == nil {
+					err = o.Set(overrideMethod(prefix, evt.Key), override)
+				}
+			}
+			if err != nil {
+				logger.Error("substratum method override reload failed", logger.Content{
+					"error": err.Error(),
+					"event": evt.EventType,
+					"key":   evt.Key,
+				})
+			}
+		}
+	}
+}
+
+func overrideMethod(prefix, key string) string {
+	return "/" + strings.TrimPrefix(strings.TrimPrefix(key, prefix), "/")
+}
+
+func parseMethodOverride(value string) (*MethodOverride, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var override MethodOverride
+	if err := json.Unmarshal([]byte(value), &override); err != nil {
+		return nil, err
+	}
+	return &override, nil
+}
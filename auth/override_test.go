@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/appootb/substratum/v2/configure"
+	"github.com/appootb/substratum/v2/errors"
+	"github.com/appootb/substratum/v2/metadata"
+	pconf "github.com/appootb/substratum/v2/plugin/configure"
+	"github.com/appootb/substratum/v2/plugin/logger"
+	"github.com/appootb/substratum/v2/proto/go/common"
+	"github.com/appootb/substratum/v2/proto/go/permission"
+	"github.com/appootb/substratum/v2/proto/go/secret"
+	"google.golang.org/grpc/codes"
+	grpcmd "google.golang.org/grpc/metadata"
+)
+
+func init() {
+	logger.Init()
+	pconf.Init()
+}
+
+func TestAlgorithmAuth_MethodOverrides(t *testing.T) {
+	parser := tokenParserFunc(func(md *common.Metadata) (*secret.Info, error) {
+		return &secret.Info{Account: 1001, Subject: permission.Subject_MOBILE, Roles: []string{"user"}}, nil
+	})
+	method := "/test.Service/Override"
+	overrides := NewMethodOverrides()
+	n := NewAlgorithmAuth(parser, parser, WithMethodOverrides(overrides)).(*AlgorithmAuth)
+	n.RegisterServiceSubjects("test", map[string][]permission.Subject{
+		method: {permission.Subject_MOBILE},
+	}, map[string][]string{
+		method: {"user"},
+	})
+
+	authenticate := func(token string) codes.Code {
+		ctx := metadata.ContextWithIncomingMetadata(grpcmd.NewIncomingContext(context.Background(),
+			grpcmd.Pairs(metadata.KeyToken, token, metadata.KeyPlatform, strconv.Itoa(int(common.Platform_PLATFORM_IOS)))))
+		_, err := n.Authenticate(ctx, method)
+		return codes.Code(errors.ErrorCode(err))
+	}
+	if code := authenticate("valid"); code != codes.OK {
+		t.Fatal("unexpected code", code)
+	}
+
+	cases := []struct {
+		value string
+		token string
+		code  codes.Code
+	}{
+		{`{"roles": ["admin"]}`, "valid", codes.PermissionDenied},
+		{`{"subjects": ["SERVER"]}`, "valid", codes.PermissionDenied},
+		{`{"subjects": ["NONE"]}`, "", codes.OK},
+		{`{"disabled": true}`, "valid", codes.PermissionDenied},
+		{`{"maintenance": true, "message": "back soon"}`, "valid", codes.Unavailable},
+	}
+	for _, c := range cases {
+		override, err := parseMethodOverride(c.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = overrides.Set(method, override); err != nil {
+			t.Fatal(err)
+		}
+		if code := authenticate(c.token); code != c.code {
+			t.Fatal(c.value, "unexpected code", code)
+		}
+	}
+
+	if err := overrides.Set(method, nil); err != nil {
+		t.Fatal(err)
+	}
+	if code := authenticate("valid"); code != codes.OK {
+		t.Fatal("unexpected code after removing the override", code)
+	}
+	if err := overrides.Set(method, &MethodOverride{Subjects: []string{"UNKNOWN"}}); err == nil {
+		t.Fatal("unknown subject accepted")
+	}
+}
+
+func TestAlgorithmAuth_OverridePrecedence(t *testing.T) {
+	method := "/test.Service/Precedence"
+	rbac := NewRBAC()
+	if err := rbac.load(`{"methods": {"/test.Service/Precedence": ["admin"]}}`); err != nil {
+		t.Fatal(err)
+	}
+	overrides := NewMethodOverrides()
+	n := NewAlgorithmAuth(nil, nil, WithPolicyEvaluator(rbac), WithMethodOverrides(overrides)).(*AlgorithmAuth)
+	n.RegisterServiceSubjects("test", map[string][]permission.Subject{
+		method: {permission.Subject_MOBILE},
+	}, map[string][]string{
+		method: {"user"},
+	})
+	user := &secret.Info{Account: 1001, Subject: permission.Subject_MOBILE, Roles: []string{"user"}}
+
+	// RBAC policy methods take precedence over the method options.
+	if _, err := n.CheckPolicy(method, user); err == nil {
+		t.Fatal("policy methods not applied")
+	}
+	// Override roles take precedence over the RBAC policy methods.
+	if err := overrides.Set(method, &MethodOverride{Roles: []string{"user"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := n.CheckPolicy(method, user); err != nil {
+		t.Fatal("override roles not applied", err)
+	}
+	if err := overrides.Set(method, &MethodOverride{Roles: []string{}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := n.CheckPolicy(method, &secret.Info{Subject: permission.Subject_MOBILE}); err != nil {
+		t.Fatal("empty override roles not applied", err)
+	}
+}
+
+func TestMethodOverrides_WatchInvalid(t *testing.T) {
+	backend := configure.BackendImplementor()
+	prefix := MethodOverridePrefix + "watch/"
+	if err := backend.Set(prefix+"test.Service/Valid", `{"disabled": true}`); err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.Set(prefix+"test.Service/Malformed", `{"disabled":`); err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.Set(prefix+"test.Service/Unknown", `{"subjects": ["UNKNOWN"]}`); err != nil {
+		t.Fatal(err)
+	}
+	overrides := NewMethodOverrides()
+	if err := overrides.Watch(prefix); err != nil {
+		t.Fatal(err)
+	}
+	if o := overrides.Get("/test.Service/Valid"); o == nil || !o.Disabled {
+		t.Fatal("valid override not loaded")
+	}
+	if overrides.Get("/test.Service/Malformed") != nil || overrides.Get("/test.Service/Unknown") != nil {
+		t.Fatal("invalid override loaded")
+	}
+	if err := backend.Set(prefix+"test.Service/Later", `{"maintenance": true}`); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100 && overrides.Get("/test.Service/Later") == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if o := overrides.Get("/test.Service/Later"); o == nil || !o.Maintenance {
+		t.Fatal("update not watched")
+	}
+}
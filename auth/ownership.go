@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/appootb/substratum/v2/proto/go/permission"
+	"github.com/appootb/substratum/v2/proto/go/secret"
+	"github.com/appootb/substratum/v2/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+var (
+	// Full method to the ownership rules declared by the method options.
+	methodOwnership sync.Map
+)
+
+// UnaryOwnershipInterceptor returns a new unary server interceptor that checks
+// the ownership rules of the method against the unmarshalled request.
+func UnaryOwnershipInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if rules := ownershipRules(info.FullMethod); len(rules) > 0 {
+			if err := CheckOwnership(rules, service.AccountSecretFromContext(ctx), req); err != nil {
+				return nil, err
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamOwnershipInterceptor returns a new streaming server interceptor that checks
+// the ownership rules of the method against each received message.
+func StreamOwnershipInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		rules := ownershipRules(info.FullMethod)
+		if len(rules) == 0 {
+			return handler(srv, stream)
+		}
+		return handler(srv, &ownershipWrapper{
+			ServerStream: stream,
+			rules:        rules,
+		})
+	}
+}
+
+type ownershipWrapper struct {
+	grpc.ServerStream
+	rules []*permission.Ownership
+}
+
+func (s *ownershipWrapper) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return CheckOwnership(s.rules, service.AccountSecretFromContext(s.Context()), m)
+}
+
+// CheckOwnership checks whether the request fields declared by the rules equal the account of the secret,
+// a rule is skipped if the secret has any of its bypass roles.
+func CheckOwnership(rules []*permission.Ownership, secretInfo *secret.Info, req interface{}) error {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return status.Error(codes.Internal, "ownership rules require a proto message")
+	}
+	account := strconv.FormatUint(secretInfo.GetAccount(), 10)
+	for _, rule := range rules {
+		if hasAnyRole(secretInfo, rule.GetBypassRoles()) {
+			continue
+		}
+		owners, err := fieldValues(msg.ProtoReflect(), rule.GetField())
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		if secretInfo.GetAccount() == 0 || len(owners) == 0 {
+			return status.Error(codes.PermissionDenied, fmt.Sprintf("resource owner required: %s", rule.GetField()))
+		}
+		for _, owner := range owners {
+			if owner != account {
+				return status.Error(codes.PermissionDenied, fmt.Sprintf("not the resource owner: %s", rule.GetField()))
+			}
+		}
+	}
+	return nil
+}
+
+func ownershipRules(fullMethod string) []*permission.Ownership {
+	if v, ok := methodOwnership.Load(fullMethod); ok {
+		return v.([]*permission.Ownership)
+	}
+	rules := lookupOwnership(fullMethod)
+	methodOwnership.Store(fullMethod, rules)
+	return rules
+}
+
+func lookupOwnership(fullMethod string) []*permission.Ownership {
+	pos := strings.LastIndex(fullMethod, "/")
+	if pos < 0 {
+		return nil
+	}
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(strings.TrimPrefix(fullMethod[:pos], "/")))
+	if err != nil {
+		return nil
+	}
+	sd, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil
+	}
+	md := sd.Methods().ByName(protoreflect.Name(fullMethod[pos+1:]))
+	if md == nil {
+		return nil
+	}
+	opts, ok := md.Options().(*descriptorpb.MethodOptions)
+	if !ok || opts == nil {
+		return nil
+	}
+	return proto.GetExtension(opts, permission.E_Ownership).([]*permission.Ownership)
+}
+
+// fieldValues returns the formatted values of the field path, e.g. `order.user_id`.
+func fieldValues(msg protoreflect.Message, path string) ([]string, error) {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		fd := msg.Descriptor().Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			return nil, fmt.Errorf("ownership field not found: %s", path)
+		}
+		if i < len(names)-1 {
+			if fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() {
+				return nil, fmt.Errorf("ownership field not a message: %s", path)
+			}
+			if !msg.Has(fd) {
+				return nil, nil
+			}
+			msg = msg.Get(fd).Message()
+			continue
+		}
+		if fd.IsMap() || fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind {
+			return nil, fmt.Errorf("ownership field not a scalar: %s", path)
+		}
+		if fd.IsList() {
+			list := msg.Get(fd).List()
+			values := make([]string, 0, list.Len())
+			for j := 0; j < list.Len(); j++ {
+				values = append(values, formatValue(list.Get(j)))
+			}
+			return values, nil
+		}
+		if !msg.Has(fd) {
+			return nil, nil
+		}
+		return []string{formatValue(msg.Get(fd))}, nil
+	}
+	return nil, nil
+}
+
+func formatValue(v protoreflect.Value) string {
+	switch i := v.Interface().(type) {
+	case string:
+		return i
+	default:
+		return fmt.Sprint(i)
+	}
+}
+
+func hasAnyRole(secretInfo *secret.Info, roles []string) bool {
+	for _, role := range roles {
+		for _, r := range secretInfo.GetRoles() {
+			if r == role {
+				return true
+			}
+		}
+	}
+	return false
+}
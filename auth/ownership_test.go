@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/appootb/substratum/v2/proto/go/permission"
+	"github.com/appootb/substratum/v2/proto/go/secret"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestCheckOwnership(t *testing.T) {
+	owner := &secret.Info{Account: 1001}
+	other := &secret.Info{Account: 1002}
+	staff := &secret.Info{Account: 1003, Roles: []string{"staff"}}
+	rules := []*permission.Ownership{
+		{Field: "account", BypassRoles: []string{"staff"}},
+	}
+	req := &secret.Info{Account: 1001}
+
+	if err := CheckOwnership(rules, owner, req); err != nil {
+		t.Fatal("owner denied")
+	}
+	if err := CheckOwnership(rules, other, req); err == nil {
+		t.Fatal("other account permitted")
+	}
+	if err := CheckOwnership(rules, staff, req); err != nil {
+		t.Fatal("bypass role denied")
+	}
+	if err := CheckOwnership(rules, &secret.Info{}, &secret.Info{}); err == nil {
+		t.Fatal("anonymous permitted")
+	}
+
+	nested := []*permission.Ownership{{Field: "issued_at.seconds"}}
+	if err := CheckOwnership(nested, owner, &secret.Info{IssuedAt: &timestamppb.Timestamp{Seconds: 1001}}); err != nil {
+		t.Fatal("nested owner denied")
+	}
+	if err := CheckOwnership(nested, owner, &secret.Info{}); err == nil {
+		t.Fatal("unset nested owner permitted")
+	}
+	if err := CheckOwnership([]*permission.Ownership{{Field: "user_id"}}, owner, req); err == nil {
+		t.Fatal("unknown field permitted")
+	}
+	if rules := ownershipRules("/grpc.health.v1.Health/Check"); len(rules) != 0 {
+		t.Fatal("unexpected ownership rules")
+	}
+}
@@ -0,0 +1,275 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/appootb/substratum/v2/configure"
+	ictx "github.com/appootb/substratum/v2/internal/context"
+	"github.com/appootb/substratum/v2/logger"
+	"github.com/appootb/substratum/v2/proto/go/secret"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RBACPolicyKey is the configure backend key of the RBAC policy.
+const RBACPolicyKey = "config/_rbac"
+
+// PolicyEvaluator decides whether the authenticated secret is permitted to call the method.
+type PolicyEvaluator interface {
+	// Evaluate the policy of the method, methodRoles is declared by the roles option of the method.
+	// Returns nil if the call is permitted.
+	Evaluate(serviceMethod string, methodRoles []string, secretInfo *secret.Info) error
+}
+
+// RoleEvaluator is implemented by the policy evaluators checking the required roles directly,
+// regardless of the requirements of the method in the policy.
+type RoleEvaluator interface {
+	// EvaluateRoles returns nil if the secret has any of the required roles or permissions.
+	EvaluateRoles(required []string, secretInfo *secret.Info) error
+}
+
+// Role of the RBAC policy.
+type Role struct {
+	// Inherits permissions of the parent roles.
+	Inherits []string `json:"inherits,omitempty"`
+	// Permissions granted, e.g. `order:read`, `order:*` or `*`.
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// Policy of the RBAC evaluator.
+type Policy struct {
+	// Roles by name.
+	Roles map[string]*Role `json:"roles,omitempty"`
+	// Methods overrides the required roles or permissions of the method declared by the options,
+	// the map key is the full url path of the method.
+	Methods map[string][]string `json:"methods,omitempty"`
+	// SuperRoles are permitted to call all the methods, defaults to the super roles of the RBAC options,
+	// an empty array disables the super roles.
+	SuperRoles []string `json:"super_roles,omitempty"`
+}
+
+type RBACOption func(*RBAC)
+
+// WithSuperRoles sets the default super roles permitted to call all the methods,
+// used unless the super roles of the policy are set. No super roles by default.
+func WithSuperRoles(roles ...string) RBACOption {
+	return func(r *RBAC) {
+		r.superRoles = roles
+	}
+}
+
+// RBAC is a role-based PolicyEvaluator with role hierarchies and permission strings.
+// A call is permitted if the secret has any of the required roles, directly or inherited,
+// or any of the granted permissions matches a requirement.
+type RBAC struct {
+	superRoles []string
+	compiled   atomic.Value
+}
+
+// NewRBAC returns an RBAC evaluator with the default policy,
+// which only permits secrets with any of the method roles or the default super roles.
+func NewRBAC(opts ...RBACOption) *RBAC {
+	r := &RBAC{}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.Update(r.defaultPolicy())
+	return r
+}
+
+// defaultPolicy returns the policy with the default super roles only.
+func (r *RBAC) defaultPolicy() *Policy {
+	return &Policy{
+		// Copied, unmarshalling the policy reuses the slice.
+		SuperRoles: append([]string(nil), r.superRoles...),
+	}
+}
+
+// Update the policy atomically.
+func (r *RBAC) Update(policy *Policy) {
+	r.compiled.Store(compilePolicy(policy))
+}
+
+// Watch loads the policy from the configure backend, and reloads it on changes.
+// The current policy is kept if the initial load fails, and is replaced by the next valid change.
+func (r *RBAC) Watch(key string) error {
+	var version uint64
+	backend := configure.BackendImplementor()
+	pairs, err := backend.Get(key, false)
+	if err == nil {
+		version = pairs.Version
+		if len(pairs.KVs) > 0 {
+			err = r.load(pairs.KVs[0].Value)
+		}
+	}
+	if err != nil {
+		logger.Error("substratum rbac policy load failed", logger.Content{
+			"error": err.Error(),
+			"key":   key,
+		})
+	}
+	evtChan, err := backend.Watch(key, version, false)
+	if err != nil {
+		return err
+	}
+	go r.watchEvent(key, evtChan)
+	return nil
+}
+
+func (r *RBAC) load(value string) error {
+	policy := r.defaultPolicy()
+	if value != "" {
+		if err := json.Unmarshal([]byte(value), policy); err != nil {
+			return err
+		}
+	}
+	r.Update(policy)
+	return nil
+}
+
+func (r *RBAC) watchEvent(key string, ch configure.EventChan) {
+	for {
+		select {
+		case <-ictx.Context.Done():
+			return
+
+		case evt := <-ch:
+			var err error
+			switch evt.EventType {
+			case configure.Delete:
+				r.Update(r.defaultPolicy())
+			case configure.Refresh:
+				var pairs *configure.KVPairs
+				if pairs, err = configure.BackendImplementor().Get(key, false); err == nil {
+					if len(pairs.KVs) > 0 {
+						err = r.load(pairs.KVs[0].Value)
+					} else {
+						r.Update(r.defaultPolicy())
+					}
+				}
+			default:
+				err = r.load(evt.Value)
+			}
+			if err != nil {
+				logger.Error("substratum rbac policy reload failed", logger.Content{
+					"error": err.Error(),
+					"event": evt.EventType,
+					"key":   key,
+				})
+			}
+		}
+	}
+}
+
+// Evaluate the policy of the method.
+func (r *RBAC) Evaluate(serviceMethod string, methodRoles []string, secretInfo *secret.Info) error {
+	policy := r.compiled.Load().(*compiledPolicy)
+	required, ok := policy.methods[serviceMethod]
+	if !ok {
+		required = methodRoles
+	}
+	return policy.evaluate(required, secretInfo)
+}
+
+// EvaluateRoles checks the required roles or permissions, the methods of the policy are ignored.
+func (r *RBAC) EvaluateRoles(required []string, secretInfo *secret.Info) error {
+	return r.compiled.Load().(*compiledPolicy).evaluate(required, secretInfo)
+}
+
+func (c *compiledPolicy) evaluate(required []string, secretInfo *secret.Info) error {
+	if len(required) == 0 {
+		return nil
+	}
+	roles, permissions := c.expand(secretInfo.GetRoles())
+	for _, super := range c.superRoles {
+		if roles[super] {
+			return nil
+		}
+	}
+	for _, req := range required {
+		if roles[req] {
+			return nil
+		}
+		for _, perm := range permissions {
+			if matchPermission(perm, req) {
+				return nil
+			}
+		}
+	}
+	return status.Error(codes.PermissionDenied,
+		fmt.Sprintf("roles: %v, expected: %v", secretInfo.GetRoles(), required))
+}
+
+type compiledPolicy struct {
+	// Role name to the role itself and all the inherited roles.
+	roles      map[string][]string
+	grants     map[string][]string
+	methods    map[string][]string
+	superRoles []string
+}
+
+func compilePolicy(policy *Policy) *compiledPolicy {
+	c := &compiledPolicy{
+		roles:      make(map[string][]string, len(policy.Roles)),
+		grants:     make(map[string][]string, len(policy.Roles)),
+		methods:    make(map[string][]string, len(policy.Methods)),
+		superRoles: policy.SuperRoles,
+	}
+	for name, role := range policy.Roles {
+		if role != nil {
+			c.grants[name] = role.Permissions
+		}
+	}
+	for name := range policy.Roles {
+		visited := map[string]bool{}
+		inheritRoles(policy.Roles, name, visited)
+		for r := range visited {
+			c.roles[name] = append(c.roles[name], r)
+		}
+	}
+	for method, required := range policy.Methods {
+		c.methods[method] = required
+	}
+	return c
+}
+
+func inheritRoles(roles map[string]*Role, name string, visited map[string]bool) {
+	if visited[name] {
+		return
+	}
+	visited[name] = true
+	if role := roles[name]; role != nil {
+		for _, parent := range role.Inherits {
+			inheritRoles(roles, parent, visited)
+		}
+	}
+}
+
+// expand returns the roles and permissions granted to the secret roles.
+func (c *compiledPolicy) expand(secretRoles []string) (map[string]bool, []string) {
+	roles := make(map[string]bool, len(secretRoles))
+	for _, name := range secretRoles {
+		roles[name] = true
+		for _, r := range c.roles[name] {
+			roles[r] = true
+		}
+	}
+	var permissions []string
+	for name := range roles {
+		permissions = append(permissions, c.grants[name]...)
+	}
+	return roles, permissions
+}
+
+// matchPermission reports whether the granted permission matches the required one,
+// `*` matches all and `order:*` matches all the permissions prefixed with `order:`.
+func matchPermission(granted, required string) bool {
+	if granted == "*" || granted == required {
+		return true
+	}
+	return strings.HasSuffix(granted, ":*") &&
+		strings.HasPrefix(required, strings.TrimSuffix(granted, "*"))
+}
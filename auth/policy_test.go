@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/appootb/substratum/v2/configure"
+	"github.com/appootb/substratum/v2/proto/go/secret"
+)
+
+func TestRBAC(t *testing.T) {
+	r := NewRBAC()
+	viewer := &secret.Info{Roles: []string{"viewer"}}
+	editor := &secret.Info{Roles: []string{"editor"}}
+	admin := &secret.Info{Roles: []string{"admin"}}
+
+	if err := r.Evaluate("/svc/Get", nil, viewer); err != nil {
+		t.Fatal("method without roles denied")
+	}
+	if err := r.Evaluate("/svc/Get", []string{"viewer"}, viewer); err != nil {
+		t.Fatal("method role denied")
+	}
+	if err := r.Evaluate("/svc/Get", []string{"viewer"}, admin); err == nil {
+		t.Fatal("admin role permitted without super roles")
+	}
+
+	if err := r.load(`{
+		"roles": {
+			"viewer": {"permissions": ["order:read"]},
+			"editor": {"inherits": ["viewer"], "permissions": ["order:write"]},
+			"admin": {"inherits": ["editor"], "permissions": ["*"]},
+			"loop": {"inherits": ["loop"]}
+		},
+		"methods": {
+			"/svc/Update": ["order:write"]
+		}
+	}`); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Evaluate("/svc/Get", []string{"order:read"}, editor); err != nil {
+		t.Fatal("inherited permission denied")
+	}
+	if err := r.Evaluate("/svc/Get", []string{"viewer"}, editor); err != nil {
+		t.Fatal("inherited role denied")
+	}
+	if err := r.Evaluate("/svc/Update", []string{"viewer"}, viewer); err == nil {
+		t.Fatal("method override not applied")
+	}
+	if err := r.Evaluate("/svc/Update", nil, editor); err != nil {
+		t.Fatal("method override denied")
+	}
+	if err := r.Evaluate("/svc/Delete", []string{"order:delete"}, admin); err != nil {
+		t.Fatal("wildcard permission denied")
+	}
+	if err := r.Evaluate("/svc/Get", []string{"order:read"}, &secret.Info{Roles: []string{"loop"}}); err == nil {
+		t.Fatal("permission granted to unrelated role")
+	}
+	if !matchPermission("order:*", "order:read") || matchPermission("order:*", "orders:read") {
+		t.Fatal("bad permission wildcard")
+	}
+}
+
+func TestRBAC_SuperRoles(t *testing.T) {
+	admin := &secret.Info{Roles: []string{"admin"}}
+	root := &secret.Info{Roles: []string{"root"}}
+
+	for _, c := range []struct {
+		name   string
+		opts   []RBACOption
+		policy string
+		admin  bool
+		root   bool
+	}{
+		{"no super roles", nil, `{}`, false, false},
+		{"policy super roles", nil, `{"super_roles": ["root"]}`, false, true},
+		{"default super roles", []RBACOption{WithSuperRoles("admin")}, `{"methods": {"/svc/Get": ["order:read"]}}`, true, false},
+		{"overridden super roles", []RBACOption{WithSuperRoles("admin")}, `{"super_roles": ["root"]}`, false, true},
+		{"disabled super roles", []RBACOption{WithSuperRoles("admin")}, `{"super_roles": []}`, false, false},
+	} {
+		r := NewRBAC(c.opts...)
+		if err := r.load(c.policy); err != nil {
+			t.Fatal(c.name, err)
+		}
+		if err := r.Evaluate("/svc/Get", []string{"viewer"}, admin); (err == nil) != c.admin {
+			t.Fatal(c.name, "unexpected admin evaluation", err)
+		}
+		if err := r.Evaluate("/svc/Get", []string{"viewer"}, root); (err == nil) != c.root {
+			t.Fatal(c.name, "unexpected root evaluation", err)
+		}
+		// The default super roles are kept after reloading.
+		if err := r.load(""); err != nil {
+			t.Fatal(c.name, err)
+		}
+		if err := r.Evaluate("/svc/Get", []string{"viewer"}, admin); (err == nil) != (len(c.opts) > 0) {
+			t.Fatal(c.name, "default super roles not restored", err)
+		}
+	}
+}
+
+func TestRBAC_WatchInvalid(t *testing.T) {
+	backend := configure.BackendImplementor()
+	key := RBACPolicyKey + "/watch"
+	if err := backend.Set(key, `{"methods":`); err != nil {
+		t.Fatal(err)
+	}
+	r := NewRBAC()
+	if err := r.Watch(key); err != nil {
+		t.Fatal(err)
+	}
+	viewer := &secret.Info{Roles: []string{"viewer"}}
+	if err := r.Evaluate("/svc/Get", []string{"viewer"}, viewer); err != nil {
+		t.Fatal("default policy not kept")
+	}
+	if err := backend.Set(key, `{"methods": {"/svc/Get": ["editor"]}}`); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100 && r.Evaluate("/svc/Get", []string{"viewer"}, viewer) == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := r.Evaluate("/svc/Get", []string{"viewer"}, viewer); err == nil {
+		t.Fatal("update not watched")
+	}
+}
@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"github.com/appootb/substratum/v2/proto/go/common"
+	"github.com/appootb/substratum/v2/proto/go/secret"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ChainTokenParser returns a token parser trying the parsers in order,
+// the error of the first parser is returned if none of them succeeds.
+func ChainTokenParser(parsers ...TokenParser) TokenParser {
+	return tokenParserChain(parsers)
+}
+
+type tokenParserChain []TokenParser
+
+func (c tokenParserChain) Parse(md *common.Metadata) (*secret.Info, error) {
+	firstErr := status.Error(codes.Unauthenticated, "substratum: no token parser")
+	for i, parser := range c {
+		secretInfo, err := parser.Parse(md)
+		if err == nil {
+			return secretInfo, nil
+		}
+		if i == 0 {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
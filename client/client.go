@@ -6,22 +6,23 @@ import (
 	"time"
 
 	ictx "github.com/appootb/substratum/v2/internal/context"
+	"github.com/appootb/substratum/v2/logger"
 	md "github.com/appootb/substratum/v2/metadata"
 	"github.com/appootb/substratum/v2/proto/go/common"
 	"github.com/appootb/substratum/v2/proto/go/permission"
-	"github.com/appootb/substratum/v2/proto/go/secret"
 	"github.com/appootb/substratum/v2/service"
-	"github.com/appootb/substratum/v2/token"
 	"github.com/appootb/substratum/v2/util/iphelper"
 	"github.com/appootb/substratum/v2/util/random"
 	"google.golang.org/grpc/metadata"
-	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 const (
 	DefaultIssuer = "appootb"
 )
 
+// WithContext returns the outgoing context with a service token signed by the server key of keyID,
+// credential.CurrentKeyID signs with the current key for rotation.
+// The token of the incoming request is dropped, and the error is logged if failed to sign.
 func WithContext(ctx context.Context, keyID int64) context.Context {
 	now := time.Now()
 	outgoingMD, ok := metadata.FromIncomingContext(ctx)
@@ -45,50 +46,33 @@ func WithContext(ctx context.Context, keyID int64) context.Context {
 			platform |= common.Platform(i)
 		}
 	}
-	secretInfo := &secret.Info{
-		Type:      secret.Type_SERVER,
-		Algorithm: secret.Algorithm_HMAC,
-		Issuer:    DefaultIssuer,
-		Account:   account,
-		KeyId:     keyID,
-		Roles:     []string{},
-		Subject:   subject,
-		IssuedAt:  timestamppb.New(now),
-		ExpiredAt: timestamppb.New(now.Add(time.Minute)),
-	}
+	issuer := DefaultIssuer
 	if pkg := outgoingMD.Get(md.KeyPackage); len(pkg) > 0 {
-		secretInfo.Issuer = pkg[0]
+		issuer = pkg[0]
+	}
+	// The token of the caller is never forwarded.
+	if val := signedToken(keyID, account, subject, platform, issuer); val != "" {
+		outgoingMD[md.KeyToken] = []string{val}
+	} else {
+		delete(outgoingMD, md.KeyToken)
 	}
-	// TODO: cache outgoing tokens
-	val, _ := token.Implementor().Generate(secretInfo)
-	outgoingMD[md.KeyToken] = []string{val}
 	outgoingMD[md.KeyPlatform] = []string{strconv.Itoa(int(platform))}
 	outgoingMD[md.KeyTimestamp] = []string{strconv.FormatInt(now.UnixNano()/1e6, 10)}
 	outgoingMD[md.KeyOriginalIP] = append(outgoingMD.Get(md.KeyOriginalIP), iphelper.LocalIP())
 	return metadata.NewOutgoingContext(ctx, outgoingMD)
 }
 
+// WithMetadata returns the outgoing context of the request metadata, signed the same as WithContext.
 func WithMetadata(incomingMD *common.Metadata, keyID int64) context.Context {
 	now := time.Now()
 	platform := common.Platform_PLATFORM_SERVER
 	if incomingMD.Platform != common.Platform_PLATFORM_UNSPECIFIED {
 		platform |= incomingMD.GetPlatform()
 	}
-	secretInfo := &secret.Info{
-		Type:      secret.Type_SERVER,
-		Algorithm: secret.Algorithm_HMAC,
-		Issuer:    DefaultIssuer,
-		KeyId:     keyID,
-		Roles:     []string{},
-		Subject:   permission.Subject_SERVER,
-		IssuedAt:  timestamppb.New(now),
-		ExpiredAt: timestamppb.New(now.Add(time.Minute)),
-	}
+	issuer := DefaultIssuer
 	if incomingMD.Package != "" {
-		secretInfo.Issuer = incomingMD.GetPackage()
+		issuer = incomingMD.GetPackage()
 	}
-	// TODO: cache outgoing tokens
-	val, _ := token.Implementor().Generate(secretInfo)
 	traceID := incomingMD.GetTraceId()
 	if traceID == "" {
 		traceID = random.String(32)
@@ -117,8 +101,22 @@ func WithMetadata(incomingMD *common.Metadata, keyID int64) context.Context {
 		md.KeyIMEI:        incomingMD.GetImei(),
 		md.KeyDeviceMac:   incomingMD.GetDeviceMac(),
 		md.KeyUserAgent:   incomingMD.GetUserAgent(),
-		md.KeyToken:       val,
 	})
+	if val := signedToken(keyID, 0, permission.Subject_SERVER, platform, issuer); val != "" {
+		outgoingMD.Set(md.KeyToken, val)
+	}
 	outgoingMD.Set(md.KeyOriginalIP, incomingMD.GetClientIp(), iphelper.LocalIP())
 	return metadata.NewOutgoingContext(ictx.Context, outgoingMD)
 }
+
+// signedToken returns the service token, or an empty string if failed to sign.
+func signedToken(keyID int64, account uint64, subject permission.Subject, platform common.Platform, issuer string) string {
+	val, err := serviceToken(keyID, account, subject, platform, issuer)
+	if err != nil {
+		logger.Error("substratum service token sign failed", logger.Content{
+			"error":  err.Error(),
+			"key_id": keyID,
+		})
+	}
+	return val
+}
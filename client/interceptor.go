@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+
+	md "github.com/appootb/substratum/v2/metadata"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// KeyIDFunc returns the server key ID for signing the service tokens to the target.
+type KeyIDFunc func(target string) int64
+
+// UnaryClientInterceptor returns a new unary client interceptor, which propagates the incoming metadata
+// and signs the service token for outgoing requests not wrapped by WithContext.
+func UnaryClientInterceptor(keyID KeyIDFunc) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(outgoingContext(ctx, keyID(cc.Target())), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor returns a new streaming client interceptor, which propagates the incoming metadata
+// and signs the service token for outgoing streams not wrapped by WithContext.
+func StreamClientInterceptor(keyID KeyIDFunc) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(outgoingContext(ctx, keyID(cc.Target())), desc, cc, method, opts...)
+	}
+}
+
+func outgoingContext(ctx context.Context, keyID int64) context.Context {
+	outgoingMD, _ := metadata.FromOutgoingContext(ctx)
+	if len(outgoingMD.Get(md.KeyToken)) > 0 {
+		return ctx
+	}
+	serviceMD, _ := metadata.FromOutgoingContext(WithContext(ctx, keyID))
+	// Keep the deadline, the metadata set by the caller overrides the service one.
+	for k, v := range outgoingMD {
+		serviceMD[k] = v
+	}
+	return metadata.NewOutgoingContext(ctx, serviceMD)
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/appootb/substratum/v2/credential"
+	md "github.com/appootb/substratum/v2/metadata"
+	"github.com/appootb/substratum/v2/util/iphelper"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestOutgoingContext(t *testing.T) {
+	if err := credential.ServerImplementor().Add(2, []byte("TestOutgoingContext")); err != nil {
+		t.Fatal(err)
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		md.KeyToken, "caller",
+		md.KeyTraceID, "trace",
+		md.KeyLocale, "zh_CN",
+		md.KeyIsDevelop, "true",
+		md.KeyOriginalIP, "10.0.0.1",
+	))
+	ctx = metadata.AppendToOutgoingContext(ctx, "custom", "value", md.KeyLocale, "en_US")
+
+	outgoingMD, _ := metadata.FromOutgoingContext(outgoingContext(ctx, 2))
+	if tokens := outgoingMD.Get(md.KeyToken); len(tokens) != 1 || tokens[0] == "caller" {
+		t.Fatal("service token not signed", tokens)
+	}
+	for k, v := range map[string]string{
+		md.KeyTraceID:   "trace",
+		md.KeyLocale:    "en_US",
+		md.KeyIsDevelop: "true",
+		"custom":        "value",
+	} {
+		if vals := outgoingMD.Get(k); len(vals) != 1 || vals[0] != v {
+			t.Fatal("unexpected metadata", k, vals)
+		}
+	}
+	if ips := outgoingMD.Get(md.KeyOriginalIP); len(ips) != 2 || ips[0] != "10.0.0.1" || ips[1] != iphelper.LocalIP() {
+		t.Fatal("unexpected forwarded IPs", ips)
+	}
+
+	// Contexts wrapped by WithContext are passed through.
+	signed := WithContext(ctx, 2)
+	if outgoingContext(signed, 2) != signed {
+		t.Fatal("signed context wrapped again")
+	}
+}
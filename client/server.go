@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"crypto/tls"
 
 	"google.golang.org/grpc"
 )
@@ -26,6 +27,41 @@ type ConnPool interface {
 	Close()
 }
 
+// TLSConfigurer is implemented by the connection pools dialing with TLS, detected by type assertion.
+type TLSConfigurer interface {
+	// SetTLSConfig sets the TLS configuration for dialing the target,
+	// an empty target sets the default one. Connections are plaintext if not set.
+	// The cached connections using the previous configuration are closed.
+	SetTLSConfig(target string, cfg *tls.Config)
+}
+
+// KeyIDSetter is implemented by the connection pools signing the service tokens, detected by type assertion.
+type KeyIDSetter interface {
+	// SetKeyID sets the server key ID for signing the service tokens to the target,
+	// an empty target sets the default one. The current key is used if not set.
+	SetKeyID(target string, keyID int64)
+}
+
+// SetTLSConfig sets the TLS configuration of the registered connection pool for dialing the target,
+// returns false if TLS is not supported by the pool.
+func SetTLSConfig(target string, cfg *tls.Config) bool {
+	configurer, ok := impl.(TLSConfigurer)
+	if ok {
+		configurer.SetTLSConfig(target, cfg)
+	}
+	return ok
+}
+
+// SetKeyID sets the server key ID of the registered connection pool for signing the service tokens to the target,
+// returns false if not supported by the pool.
+func SetKeyID(target string, keyID int64) bool {
+	setter, ok := impl.(KeyIDSetter)
+	if ok {
+		setter.SetKeyID(target, keyID)
+	}
+	return ok
+}
+
 type connPoolKey struct{}
 
 // UnaryServerInterceptor returns a new unary server interceptor for gRPC client connection pool.
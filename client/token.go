@@ -0,0 +1,80 @@
+package client
+
+import (
+	"time"
+
+	"github.com/appootb/substratum/v2/proto/go/common"
+	"github.com/appootb/substratum/v2/proto/go/permission"
+	"github.com/appootb/substratum/v2/proto/go/secret"
+	"github.com/appootb/substratum/v2/token"
+	"github.com/appootb/substratum/v2/util/cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	DefaultTokenCacheSize = 4096
+	// Lifetime of the outgoing service tokens.
+	TokenExpiration = time.Minute
+	// Cached tokens are renewed before expiration, for clock skew and request latency.
+	TokenRenewBefore = time.Second * 15
+)
+
+var (
+	tokenCache = cache.New(cache.LRU, cache.WithSize(DefaultTokenCacheSize))
+
+	tokenCacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "substratum",
+		Subsystem: "client",
+		Name:      "token_cache_requests_total",
+		Help:      "Total number of outgoing service token lookups, by result of hit or miss.",
+	}, []string{"result"})
+)
+
+func init() {
+	prometheus.MustRegister(tokenCacheRequests)
+}
+
+type tokenCacheKey struct {
+	keyID    int64
+	account  uint64
+	subject  permission.Subject
+	platform common.Platform
+	issuer   string
+}
+
+// serviceToken returns the cached service token, or generates a new one if not cached or about to expire.
+// The token is signed with the algorithm of the server key.
+func serviceToken(keyID int64, account uint64, subject permission.Subject, platform common.Platform, issuer string) (string, error) {
+	key := tokenCacheKey{
+		keyID:    keyID,
+		account:  account,
+		subject:  subject,
+		platform: platform,
+		issuer:   issuer,
+	}
+	result := "hit"
+	val, err := tokenCache.GetOrLoad(key, func(interface{}) (interface{}, time.Duration, error) {
+		result = "miss"
+		now := time.Now()
+		val, err := token.Implementor().Generate(&secret.Info{
+			Type:      secret.Type_SERVER,
+			Issuer:    issuer,
+			Account:   account,
+			KeyId:     keyID,
+			Roles:     []string{},
+			Subject:   subject,
+			IssuedAt:  timestamppb.New(now),
+			ExpiredAt: timestamppb.New(now.Add(TokenExpiration)),
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		return val, TokenExpiration - TokenRenewBefore, nil
+	})
+	tokenCacheRequests.WithLabelValues(result).Inc()
+	if err != nil {
+		return "", err
+	}
+	return val.(string), nil
+}
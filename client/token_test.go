@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/appootb/substratum/v2/credential"
+	md "github.com/appootb/substratum/v2/metadata"
+	pc "github.com/appootb/substratum/v2/plugin/credential"
+	"github.com/appootb/substratum/v2/plugin/logger"
+	pt "github.com/appootb/substratum/v2/plugin/token"
+	"github.com/appootb/substratum/v2/proto/go/common"
+	"github.com/appootb/substratum/v2/proto/go/permission"
+	"github.com/appootb/substratum/v2/proto/go/secret"
+	"github.com/appootb/substratum/v2/token"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc/metadata"
+)
+
+func init() {
+	logger.Init()
+	pc.Init()
+	pt.Init()
+}
+
+func TestServiceToken(t *testing.T) {
+	if err := credential.ServerImplementor().Add(1, []byte("TestServiceToken")); err != nil {
+		t.Fatal(err)
+	}
+	hit := testutil.ToFloat64(tokenCacheRequests.WithLabelValues("hit"))
+	miss := testutil.ToFloat64(tokenCacheRequests.WithLabelValues("miss"))
+
+	val, err := serviceToken(1, 123456789, permission.Subject_SERVER, common.Platform_PLATFORM_SERVER, DefaultIssuer)
+	if err != nil || val == "" {
+		t.Fatal("empty token", err)
+	}
+	if cached, _ := serviceToken(1, 123456789, permission.Subject_SERVER, common.Platform_PLATFORM_SERVER, DefaultIssuer); cached != val {
+		t.Fatal("token not cached")
+	}
+	if other, _ := serviceToken(1, 987654321, permission.Subject_SERVER, common.Platform_PLATFORM_SERVER, DefaultIssuer); other == val {
+		t.Fatal("token shared between accounts")
+	}
+	if testutil.ToFloat64(tokenCacheRequests.WithLabelValues("hit"))-hit != 1 ||
+		testutil.ToFloat64(tokenCacheRequests.WithLabelValues("miss"))-miss != 2 {
+		t.Fatal("unexpected cache metrics")
+	}
+}
+
+func TestServiceToken_AsymmetricKey(t *testing.T) {
+	key, err := token.Implementor().NewSecretKey(secret.Algorithm_EdDSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = credential.ServerImplementor().(credential.ServerKeyStore).AddKey(&credential.ServerKey{
+		ID:        3,
+		Key:       key,
+		Algorithm: secret.Algorithm_EdDSA,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	val, err := serviceToken(3, 123456789, permission.Subject_SERVER, common.Platform_PLATFORM_SERVER, DefaultIssuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := token.Implementor().ParseRaw(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.GetAlgorithm() != secret.Algorithm_EdDSA {
+		t.Fatal("signed with", s.GetAlgorithm())
+	}
+}
+
+func TestServiceToken_SignFailed(t *testing.T) {
+	if _, err := serviceToken(404, 123456789, permission.Subject_SERVER, common.Platform_PLATFORM_SERVER, DefaultIssuer); err == nil {
+		t.Fatal("signed with an unknown key")
+	}
+	outgoingMD, _ := metadata.FromOutgoingContext(WithContext(context.Background(), 404))
+	if _, ok := outgoingMD[md.KeyToken]; ok {
+		t.Fatal("token set without signing")
+	}
+	outgoingMD, _ = metadata.FromOutgoingContext(WithMetadata(&common.Metadata{Token: "caller"}, 404))
+	if _, ok := outgoingMD[md.KeyToken]; ok {
+		t.Fatal("token set without signing")
+	}
+
+	// The token of the caller is dropped.
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(md.KeyToken, "caller"))
+	outgoingMD, _ = metadata.FromOutgoingContext(WithContext(ctx, 404))
+	if _, ok := outgoingMD[md.KeyToken]; ok {
+		t.Fatal("caller token forwarded", outgoingMD.Get(md.KeyToken))
+	}
+	outgoingMD, _ = metadata.FromOutgoingContext(outgoingContext(ctx, 404))
+	if _, ok := outgoingMD[md.KeyToken]; ok {
+		t.Fatal("caller token forwarded", outgoingMD.Get(md.KeyToken))
+	}
+}
@@ -1,6 +1,8 @@
 package substratum
 
 import (
+	"context"
+
 	"github.com/appootb/substratum/v2/configure"
 	"github.com/appootb/substratum/v2/queue"
 	"github.com/appootb/substratum/v2/service"
@@ -39,3 +41,24 @@ type Component interface {
 	// ScheduleCronTask schedules the cron tasks.
 	ScheduleCronTask(task.Task) error
 }
+
+// Optional component interfaces, detected by type assertion.
+
+// Starter is implemented by components running background work.
+type Starter interface {
+	// Start is invoked when serving, in registration order.
+	Start(ctx context.Context) error
+}
+
+// Stopper is implemented by components that must be stopped before exit.
+type Stopper interface {
+	// Stop is invoked when shutting down, in reverse registration order.
+	// Only the components started are stopped if serving fails.
+	Stop(ctx context.Context) error
+}
+
+// HealthChecker is implemented by components reporting health status.
+type HealthChecker interface {
+	// Health returns a non-nil error if the component is unhealthy.
+	Health(ctx context.Context) error
+}
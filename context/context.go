@@ -9,6 +9,7 @@ import (
 	"github.com/appootb/substratum/v2/logger"
 	"github.com/appootb/substratum/v2/queue"
 	"github.com/appootb/substratum/v2/service"
+	"github.com/appootb/substratum/v2/snowflake"
 	"github.com/appootb/substratum/v2/storage"
 	"github.com/appootb/substratum/v2/task"
 )
@@ -31,6 +32,7 @@ func WithServerContext(ctx context.Context, component string) context.Context {
 			logger.ContextWithLogger(
 				queue.ContextWithQueueService(
 					storage.ContextWithStorage(
-						task.ContextWithTaskService(
-							service.ContextWithComponentName(ctx, component)))))))
+						snowflake.ContextWithSnowflake(
+							task.ContextWithTaskService(
+								service.ContextWithComponentName(ctx, component))))))))
 }
@@ -0,0 +1,44 @@
+package credential
+
+import (
+	"time"
+
+	"github.com/appootb/substratum/v2/proto/go/permission"
+)
+
+var (
+	apiKeyImpl APIKeyStore
+)
+
+// APIKeyImplementor returns the API key store implementor.
+func APIKeyImplementor() APIKeyStore {
+	return apiKeyImpl
+}
+
+// RegisterAPIKeyImplementor registers the API key store implementor.
+func RegisterAPIKeyImplementor(store APIKeyStore) {
+	apiKeyImpl = store
+}
+
+// APIKey is a long-lived secret key of the partner integrations for signing requests.
+type APIKey struct {
+	ID      int64              `json:"id"`
+	Account uint64             `json:"account"`
+	Secret  []byte             `json:"secret"`
+	Subject permission.Subject `json:"subject"`
+	Roles   []string           `json:"roles"`
+	// Zero value means never expires.
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+// APIKeyStore stores the API keys, the key IDs are unique across accounts.
+type APIKeyStore interface {
+	// Add a new API key.
+	Add(key *APIKey) error
+	// Get the API key of the specified ID.
+	Get(keyID int64) (*APIKey, error)
+	// List the API keys of the user.
+	List(uid uint64) ([]*APIKey, error)
+	// Revoke the API key of the specified ID.
+	Revoke(keyID int64) error
+}
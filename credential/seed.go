@@ -46,6 +46,13 @@ type Client interface {
 	Unlock(uid uint64) error
 }
 
+// ClientSwapper is implemented by the client seeds replacing the secret keys atomically,
+// which is required by the rotation of the refresh tokens.
+type ClientSwapper interface {
+	// CompareAndSwap replaces the secret key if its current value is old, returns false if not replaced.
+	CompareAndSwap(uid uint64, keyID int64, old, val []byte, expire time.Duration) (bool, error)
+}
+
 // Server secret key.
 type Server interface {
 	// Add a new secret key for the specified ID.
@@ -55,3 +62,42 @@ type Server interface {
 	// Revoke the secret key of the specified ID.
 	Revoke(keyID int64) error
 }
+
+// ServerKeyStore is implemented by the server seeds keeping the algorithms and validity windows of the keys,
+// which is required by the key rotation and the published JWKS.
+type ServerKeyStore interface {
+	// AddKey adds or replaces a secret key with the validity window,
+	// keys could be added ahead of time for rotation.
+	AddKey(key *ServerKey) error
+	// GetKey returns the secret key of the specified ID within its validity window.
+	GetKey(keyID int64) (*ServerKey, error)
+	// Current returns the signing key, which is the latest started one within its validity window.
+	Current() (*ServerKey, error)
+	// Keys returns the secret keys not expired, including the ones not started yet.
+	Keys() ([]*ServerKey, error)
+}
+
+// GetServerKey returns the secret key of the specified ID,
+// the algorithm is inferred from the key encoding if the seed is not a ServerKeyStore.
+func GetServerKey(srv Server, keyID int64) (*ServerKey, error) {
+	if store, ok := srv.(ServerKeyStore); ok {
+		return store.GetKey(keyID)
+	}
+	key, err := srv.Get(keyID)
+	if err != nil {
+		return nil, err
+	}
+	return &ServerKey{
+		ID:  keyID,
+		Key: key,
+	}, nil
+}
+
+// CurrentServerKey returns the signing key,
+// which is the key of CurrentKeyID if the seed is not a ServerKeyStore.
+func CurrentServerKey(srv Server) (*ServerKey, error) {
+	if store, ok := srv.(ServerKeyStore); ok {
+		return store.Current()
+	}
+	return GetServerKey(srv, CurrentKeyID)
+}
@@ -0,0 +1,117 @@
+package credential
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"time"
+
+	"github.com/appootb/substratum/v2/proto/go/secret"
+	"github.com/appootb/substratum/v2/util/jwk"
+)
+
+// CurrentKeyID signs the server tokens with the current signing key.
+const CurrentKeyID int64 = 0
+
+// ServerKey is a server secret key with the validity window.
+type ServerKey struct {
+	ID  int64  `json:"id"`
+	Key []byte `json:"key"`
+	// Signing algorithm of the key, inferred from the key encoding if not set.
+	Algorithm secret.Algorithm `json:"algorithm,omitempty"`
+	// Zero value means valid since added.
+	NotBefore time.Time `json:"not_before"`
+	// Zero value means never expires.
+	NotAfter time.Time `json:"not_after"`
+}
+
+// KeyAlgorithm returns the signing algorithm of the key. If not set, PKCS1 RSA private keys are RSA,
+// EC private keys are ECDSA, PKIX public keys follow the key type, and the others are HMAC.
+// Ed25519 and RSA-PSS keys must be set explicitly.
+func (k *ServerKey) KeyAlgorithm() secret.Algorithm {
+	if k.Algorithm != secret.Algorithm_None {
+		return k.Algorithm
+	}
+	if _, err := x509.ParsePKCS1PrivateKey(k.Key); err == nil {
+		return secret.Algorithm_RSA
+	}
+	if _, err := x509.ParseECPrivateKey(k.Key); err == nil {
+		return secret.Algorithm_ECDSA
+	}
+	if pub, err := x509.ParsePKIXPublicKey(k.Key); err == nil {
+		switch pub.(type) {
+		case *rsa.PublicKey:
+			return secret.Algorithm_RSA
+		case *ecdsa.PublicKey:
+			return secret.Algorithm_ECDSA
+		case ed25519.PublicKey:
+			return secret.Algorithm_EdDSA
+		}
+		return secret.Algorithm_None
+	}
+	return secret.Algorithm_HMAC
+}
+
+// PublicKey returns the PKIX DER encoded public key of the asymmetric key by its algorithm,
+// jwk.ErrSymmetricKey is returned for HMAC keys.
+func (k *ServerKey) PublicKey() ([]byte, error) {
+	switch k.KeyAlgorithm() {
+	case secret.Algorithm_HMAC:
+		return nil, jwk.ErrSymmetricKey
+	case secret.Algorithm_EdDSA:
+		return jwk.Ed25519PublicKey(k.Key)
+	default:
+		return jwk.PublicKey(k.Key)
+	}
+}
+
+// Valid returns true if the key is within its validity window.
+func (k *ServerKey) Valid(t time.Time) bool {
+	return !t.Before(k.NotBefore) && !k.Expired(t)
+}
+
+// Expired returns true if the key is beyond its validity window.
+func (k *ServerKey) Expired(t time.Time) bool {
+	return !k.NotAfter.IsZero() && !t.Before(k.NotAfter)
+}
+
+// CurrentKey returns the latest started key within its validity window, nil if none.
+func CurrentKey(keys []*ServerKey, t time.Time) *ServerKey {
+	var current *ServerKey
+	for _, k := range keys {
+		if !k.Valid(t) {
+			continue
+		}
+		if current == nil || k.NotBefore.After(current.NotBefore) ||
+			k.NotBefore.Equal(current.NotBefore) && k.ID > current.ID {
+			current = k
+		}
+	}
+	return current
+}
+
+// Rotate adds the next signing key ahead of time, which becomes the current key at next.NotBefore.
+// The keys valid at the time are kept for verification within the overlap, and expire afterwards.
+func Rotate(srv ServerKeyStore, next *ServerKey, overlap time.Duration) error {
+	keys, err := srv.Keys()
+	if err != nil {
+		return err
+	}
+	if err = srv.AddKey(next); err != nil {
+		return err
+	}
+	notAfter := next.NotBefore.Add(overlap)
+	for _, k := range keys {
+		if k.ID == next.ID || !k.Valid(next.NotBefore) {
+			continue
+		}
+		if k.NotAfter.IsZero() || k.NotAfter.After(notAfter) {
+			k.NotAfter = notAfter
+			if err = srv.AddKey(k); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
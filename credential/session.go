@@ -0,0 +1,47 @@
+package credential
+
+import (
+	"time"
+
+	"github.com/appootb/substratum/v2/proto/go/common"
+)
+
+var (
+	sessionImpl SessionStore
+)
+
+// SessionImplementor returns the client session store implementor.
+func SessionImplementor() SessionStore {
+	return sessionImpl
+}
+
+// RegisterSessionImplementor registers the client session store implementor.
+func RegisterSessionImplementor(store SessionStore) {
+	sessionImpl = store
+}
+
+// Session of the client secret key.
+type Session struct {
+	KeyID     int64           `json:"key_id"`
+	Platform  common.Platform `json:"platform"`
+	DeviceID  string          `json:"device_id"`
+	IssuedAt  time.Time       `json:"issued_at"`
+	ExpiredAt time.Time       `json:"expired_at"`
+	// Key ID of the paired refresh token, revoked with the session.
+	RefreshID int64 `json:"refresh_id,omitempty"`
+}
+
+// SessionStore records the client sessions and the revoked key IDs.
+type SessionStore interface {
+	// AddSession records a new session of the user, and removes its key ID from the revocation list.
+	AddSession(uid uint64, session *Session) error
+	// RefreshSession renews the expiration of the session.
+	RefreshSession(uid uint64, keyID int64, expiredAt time.Time) error
+	// ListSessions returns the active sessions of the user.
+	ListSessions(uid uint64) ([]*Session, error)
+	// RevokeSession removes the session and revokes its secret key and paired refresh token,
+	// the key ID is kept in the revocation list until the session expires.
+	RevokeSession(uid uint64, keyID int64) error
+	// IsRevoked returns true if the key ID is in the revocation list.
+	IsRevoked(uid uint64, keyID int64) (bool, error)
+}
@@ -65,3 +65,18 @@ type Backend interface {
 	// Close the provider connection.
 	Close()
 }
+
+// Deleter is implemented by the backends able to delete keys before they expire,
+// detected by type assertion.
+type Deleter interface {
+	// Delete the specified key and stop keeping it alive.
+	Delete(key string) error
+}
+
+// Creator is implemented by the backends able to create keys atomically,
+// detected by type assertion.
+type Creator interface {
+	// Create sets value and keeps alive with the ttl for the specified key only if the key does not exist,
+	// false is returned if the key already exists.
+	Create(key, value string, ttl time.Duration) (bool, error)
+}
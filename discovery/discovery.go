@@ -26,3 +26,10 @@ type Discovery interface {
 	// GetAddresses returns rpc service addresses.
 	GetAddresses(service string) []resolver.Address
 }
+
+// Deregisterer is implemented by the discoveries able to remove the registered addresses,
+// detected by type assertion.
+type Deregisterer interface {
+	// Deregister removes the rpc address registered for the component.
+	Deregister(component string) error
+}
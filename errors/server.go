@@ -52,36 +52,59 @@ func UnaryResponseInterceptor() grpc.UnaryServerInterceptor {
 			return resp, err
 		}
 
-		//
-		locale := "en"
-		if incomingMD := md.IncomingMetadata(ctx); incomingMD != nil {
-			locale = incomingMD.GetLocale()
-		}
-
-		// Update error message.
-		if se, ok := err.(*StatusError); ok {
-			if message := Implementor().Translate(locale, se.Code); message != "" {
-				se.Message = message
-			}
-			outgoingMD.Set("code", strconv.Itoa(int(se.Code)))
-			outgoingMD.Set("message", url.QueryEscape(se.Message))
-			return resp, status.ErrorProto((*spb.Status)(se))
-		} else if s, ok := status.FromError(err); ok {
-			sp := s.Proto()
-			if message := Implementor().Translate(locale, sp.GetCode()); message != "" {
-				sp.Message = message
-			}
-			outgoingMD.Set("code", strconv.Itoa(int(sp.GetCode())))
-			outgoingMD.Set("message", url.QueryEscape(sp.GetMessage()))
-			err = status.ErrorProto(sp)
-		}
+		code, message, err := translateError(ctx, err)
+		outgoingMD.Set("code", strconv.Itoa(int(code)))
+		outgoingMD.Set("message", url.QueryEscape(message))
 		return resp, err
 	}
 }
 
+// StreamServerInterceptor returns a new streaming server interceptor for translating the error message,
+// the code and message are set to the trailers since the headers might have been sent.
 func StreamServerInterceptor() grpc.StreamServerInterceptor {
-	// TODO
 	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-		return handler(srv, stream)
+		outgoingMD := metadata.MD{
+			"code":    []string{"0"},
+			"message": []string{""},
+		}
+		defer func() {
+			outgoingMD.Set("timestamp", strconv.FormatInt(time.Now().Unix(), 10))
+			stream.SetTrailer(outgoingMD)
+		}()
+
+		err := handler(srv, stream)
+		if err == nil {
+			return nil
+		}
+
+		code, message, err := translateError(stream.Context(), err)
+		outgoingMD.Set("code", strconv.Itoa(int(code)))
+		outgoingMD.Set("message", url.QueryEscape(message))
+		return err
+	}
+}
+
+// translateError converts the error to gRPC status with the message translated by the request locale,
+// returns the code and translated message of the status.
+func translateError(ctx context.Context, err error) (int32, string, error) {
+	locale := "en"
+	if incomingMD := md.IncomingMetadata(ctx); incomingMD != nil {
+		locale = incomingMD.GetLocale()
+	}
+
+	// Update error message.
+	if se, ok := err.(*StatusError); ok {
+		if message := Implementor().Translate(locale, se.Code); message != "" {
+			se.Message = message
+		}
+		return se.Code, se.Message, status.ErrorProto((*spb.Status)(se))
+	} else if s, ok := status.FromError(err); ok {
+		sp := s.Proto()
+		if message := Implementor().Translate(locale, sp.GetCode()); message != "" {
+			sp.Message = message
+		}
+		return sp.GetCode(), sp.GetMessage(), status.ErrorProto(sp)
 	}
+	// Other errors are returned as is, with the code of 0 and an empty message.
+	return 0, "", err
 }
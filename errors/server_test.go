@@ -0,0 +1,84 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	md "github.com/appootb/substratum/v2/metadata"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type prompter map[string]map[int32]string
+
+func (p prompter) Translate(lang string, code int32) string {
+	return p[lang][code]
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	trailer metadata.MD
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) SetTrailer(trailer metadata.MD) {
+	s.trailer = metadata.Join(s.trailer, trailer)
+}
+
+func localeContext(locale string) context.Context {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(md.KeyLocale, locale))
+	return md.ContextWithIncomingMetadata(ctx)
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	RegisterImplementor(prompter{
+		"zh_CN": {int32(codes.NotFound): "未找到"},
+	})
+	interceptor := StreamServerInterceptor()
+
+	for _, c := range []struct {
+		locale  string
+		err     error
+		code    string
+		message string
+	}{
+		{"zh_CN", nil, "0", ""},
+		{"zh_CN", errors.New("plain"), "0", ""},
+		{"zh_CN", New(codes.NotFound, "not found"), "5", "未找到"},
+		{"zh_CN", status.Error(codes.NotFound, "not found"), "5", "未找到"},
+		{"en_US", New(codes.NotFound, "not found"), "5", "not found"},
+		{"en_US", status.Error(codes.NotFound, "not found"), "5", "not found"},
+	} {
+		stream := &serverStream{ctx: localeContext(c.locale)}
+		err := interceptor(nil, stream, &grpc.StreamServerInfo{}, func(interface{}, grpc.ServerStream) error {
+			return c.err
+		})
+		if c.err == nil && err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		if c.code != "0" {
+			if s, _ := status.FromError(err); s.Message() != c.message {
+				t.Fatal("unexpected status message", c.locale, s.Message())
+			}
+		} else if err != c.err {
+			t.Fatal("error not returned as is", err)
+		}
+		if code := stream.trailer.Get("code"); len(code) != 1 || code[0] != c.code {
+			t.Fatal("unexpected trailer code", c.err, code)
+		}
+		if message := stream.trailer.Get("message"); len(message) != 1 || message[0] != url.QueryEscape(c.message) {
+			t.Fatal("unexpected trailer message", c.err, message)
+		}
+		if len(stream.trailer.Get("timestamp")) != 1 {
+			t.Fatal("trailer timestamp not set")
+		}
+	}
+}
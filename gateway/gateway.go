@@ -1,13 +1,16 @@
 package gateway
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/textproto"
 	"strings"
 
 	md "github.com/appootb/substratum/v2/metadata"
+	"github.com/appootb/substratum/v2/token"
 	"github.com/appootb/substratum/v2/util/jsonpb"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	spb "google.golang.org/genproto/googleapis/rpc/status"
@@ -19,6 +22,8 @@ import (
 const (
 	MetadataHeaderPrefix  = "Appootb-"
 	MetadataTrailerPrefix = "Trailer-"
+	// MaxBodyDigestSize is the max size of the request bodies digested for API key signatures.
+	MaxBodyDigestSize = 4 << 20
 )
 
 var DefaultOptions = []runtime.ServeMuxOption{
@@ -26,6 +31,7 @@ var DefaultOptions = []runtime.ServeMuxOption{
 	runtime.WithIncomingHeaderMatcher(IncomingHeaderMatcher),
 	runtime.WithOutgoingHeaderMatcher(OutgoingHeaderMatcher),
 	runtime.WithMetadata(URLQueryMetadata),
+	runtime.WithMetadata(BodyDigestMetadata),
 	runtime.WithErrorHandler(ProtoErrorHandler),
 	runtime.WithStreamErrorHandler(StreamErrorHandler),
 }
@@ -74,7 +80,12 @@ func IncomingHeaderMatcher(key string) (string, bool) {
 	if isPermanentHTTPHeader(key) {
 		return key, true
 	} else if strings.HasPrefix(key, MetadataHeaderPrefix) {
-		return key[len(MetadataHeaderPrefix):], true
+		key = key[len(MetadataHeaderPrefix):]
+		// The body digest is set by the gateway only.
+		if strings.EqualFold(key, md.KeyBodyDigest) {
+			return "", false
+		}
+		return key, true
 	}
 	return "", false
 }
@@ -101,6 +112,33 @@ func URLQueryMetadata(_ context.Context, r *http.Request) metadata.MD {
 	return queryMD
 }
 
+// BodyDigestMetadata exposes the digest of the raw request body for verifying the requests signed by API keys,
+// the body is restored for decoding. Bodies of other requests are not read, and bodies larger than
+// MaxBodyDigestSize are not digested.
+func BodyDigestMetadata(_ context.Context, r *http.Request) metadata.MD {
+	if !strings.HasPrefix(requestToken(r), token.APIKeyScheme) {
+		return nil
+	}
+	var body []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		var err error
+		if body, err = ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, MaxBodyDigestSize)); err != nil {
+			return nil
+		}
+		_ = r.Body.Close()
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	return metadata.Pairs(md.KeyBodyDigest, token.BodyDigest(body))
+}
+
+// requestToken returns the token of the request metadata header or the URL query.
+func requestToken(r *http.Request) string {
+	if val := r.Header.Get(MetadataHeaderPrefix + md.KeyToken); val != "" {
+		return val
+	}
+	return r.URL.Query().Get(md.KeyToken)
+}
+
 func ProtoErrorHandler(ctx context.Context, _ *runtime.ServeMux, marshaler runtime.Marshaler,
 	w http.ResponseWriter, _ *http.Request, err error) {
 	//
@@ -0,0 +1,37 @@
+package gateway
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	md "github.com/appootb/substratum/v2/metadata"
+	"github.com/appootb/substratum/v2/token"
+)
+
+func TestBodyDigestMetadata(t *testing.T) {
+	body := `{"id":1}`
+	r := httptest.NewRequest("POST", "/v1/test", strings.NewReader(body))
+	if val := BodyDigestMetadata(context.Background(), r); val != nil {
+		t.Fatal("body digested without api key", val)
+	}
+
+	r = httptest.NewRequest("POST", "/v1/test", strings.NewReader(body))
+	r.Header.Set(MetadataHeaderPrefix+md.KeyToken, token.APIKeyScheme+"1:signature")
+	val := BodyDigestMetadata(context.Background(), r)
+	if got := val.Get(md.KeyBodyDigest); len(got) != 1 || got[0] != token.BodyDigest([]byte(body)) {
+		t.Fatal("unexpected digest", got)
+	}
+	if buf, _ := ioutil.ReadAll(r.Body); string(buf) != body {
+		t.Fatal("body not restored")
+	}
+
+	r = httptest.NewRequest("POST", "/v1/test?token="+url.QueryEscape(token.APIKeyScheme+"1:signature"),
+		strings.NewReader(strings.Repeat("a", MaxBodyDigestSize+1)))
+	if val = BodyDigestMetadata(context.Background(), r); val != nil {
+		t.Fatal("oversized body digested")
+	}
+}
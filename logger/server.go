@@ -18,6 +18,7 @@ const (
 	UpstreamLog   = "_MSG_.upstream"
 	DownstreamLog = "_MSG_.downstream"
 	StreamingLog  = "_MSG_.streaming"
+	AuditLog      = "_MSG_.audit"
 
 	Consumed      = "consumed"
 	LogConsumed   = LogTag + Consumed
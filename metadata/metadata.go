@@ -37,6 +37,7 @@ const (
 	KeyDeviceMac   = "mac"
 	KeyUserAgent   = "ua"
 	KeyToken       = "token"
+	KeyBodyDigest  = "digest"
 
 	KeyIANAUserAgent = "user-agent"
 	KeyOriginalIP    = "x-forwarded-for"
@@ -132,6 +133,7 @@ func ParseIncomingMetadata(ctx context.Context) *common.Metadata {
 		DeviceMac:   md.Get(KeyDeviceMac),
 		ClientIp:    clientIP,
 		UserAgent:   userAgent,
+		BodyDigest:  md.Get(KeyBodyDigest),
 		Token:       md.Get(KeyToken),
 	}
 }
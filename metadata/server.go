@@ -35,6 +35,11 @@ func (s *ctxWrapper) Context() context.Context {
 	return context.WithValue(ctx, metadataKey{}, md)
 }
 
+// ContextWithIncomingMetadata parses the incoming metadata into the context.
+func ContextWithIncomingMetadata(ctx context.Context) context.Context {
+	return context.WithValue(ctx, metadataKey{}, ParseIncomingMetadata(ctx))
+}
+
 func IncomingMetadata(ctx context.Context) *common.Metadata {
 	if md := ctx.Value(metadataKey{}); md != nil {
 		return md.(*common.Metadata)
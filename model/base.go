@@ -11,8 +11,10 @@ import (
 	"github.com/appootb/substratum/v2/proto/go/secret"
 	"github.com/appootb/substratum/v2/queue"
 	"github.com/appootb/substratum/v2/service"
+	"github.com/appootb/substratum/v2/snowflake"
 	"github.com/appootb/substratum/v2/storage"
 	"github.com/appootb/substratum/v2/task"
+	sf "github.com/appootb/substratum/v2/util/snowflake"
 	"google.golang.org/grpc"
 )
 
@@ -61,6 +63,11 @@ func (m *Base) Storage() storage.Storage {
 	return storage.ContextStorage(m.ctx, component)
 }
 
+func (m *Base) Snowflake() *sf.Snowflake {
+	component := service.ComponentNameFromContext(m.Context())
+	return snowflake.ContextSnowflake(m.ctx, component)
+}
+
 func (m *Base) ClientConn(target string) *grpc.ClientConn {
 	return client.ContextConnPool(m.ctx).Get(target)
 }
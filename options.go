@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/appootb/substratum/v2/proto/go/permission"
+	"github.com/appootb/substratum/v2/server"
 )
 
 const (
@@ -24,12 +25,12 @@ func WithDefaultServerMux() ServerOption {
 	return WithServeMux(permission.VisibleScope_SERVER, DefaultServerRpcPort, DefaultServerGatewayPort)
 }
 
-func WithServeMux(scope permission.VisibleScope, rpcPort, gatewayPort uint16) ServerOption {
+func WithServeMux(scope permission.VisibleScope, rpcPort, gatewayPort uint16, opts ...server.Option) ServerOption {
 	return func(s *Server) {
 		if _, ok := s.serveMuxers[scope]; ok {
 			return
 		}
-		err := s.AddServeMux(scope, rpcPort, gatewayPort)
+		err := s.AddServeMux(scope, rpcPort, gatewayPort, opts...)
 		if err != nil {
 			panic(err)
 		}
@@ -47,3 +48,15 @@ func WithKeepAliveTTL(ttl time.Duration) ServerOption {
 		s.keepAliveTTL = ttl
 	}
 }
+
+func WithHealthCheckInterval(interval time.Duration) ServerOption {
+	return func(s *Server) {
+		s.healthInterval = interval
+	}
+}
+
+func WithShutdownTimeout(timeout time.Duration) ServerOption {
+	return func(s *Server) {
+		s.shutdownTimeout = timeout
+	}
+}
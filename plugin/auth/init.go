@@ -2,11 +2,30 @@ package auth
 
 import (
 	"github.com/appootb/substratum/v2/auth"
+	"github.com/appootb/substratum/v2/logger"
+	pt "github.com/appootb/substratum/v2/plugin/token"
 	"github.com/appootb/substratum/v2/token"
 )
 
 func Init() {
 	if auth.Implementor() == nil {
-		auth.RegisterImplementor(auth.NewAlgorithmAuth(token.Implementor(), token.Implementor()))
+		rbac := auth.NewRBAC()
+		if err := rbac.Watch(auth.RBACPolicyKey); err != nil {
+			logger.Error("substratum rbac policy watch failed", logger.Content{
+				"error": err.Error(),
+				"key":   auth.RBACPolicyKey,
+			})
+		}
+		overrides := auth.NewMethodOverrides()
+		if err := overrides.Watch(auth.MethodOverridePrefix); err != nil {
+			logger.Error("substratum method overrides load failed", logger.Content{
+				"error": err.Error(),
+				"key":   auth.MethodOverridePrefix,
+			})
+		}
+		auth.RegisterImplementor(auth.NewAlgorithmAuth(token.Implementor(), token.Implementor(),
+			auth.WithAPIKeyParser(pt.NewAPIKeyParser(pt.DefaultReplayWindow)),
+			auth.WithMethodOverrides(overrides),
+			auth.WithPolicyEvaluator(rbac)))
 	}
 }
@@ -1,14 +1,17 @@
 package client
 
 import (
+	"crypto/tls"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/appootb/substratum/v2/balancer"
 	"github.com/appootb/substratum/v2/client"
+	"github.com/appootb/substratum/v2/credential"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
@@ -20,6 +23,8 @@ func Init() {
 
 type ConnPool struct {
 	sync.Map
+	tlsConfigs sync.Map
+	keyIDs     sync.Map
 }
 
 func (p *ConnPool) Get(target string) *grpc.ClientConn {
@@ -31,10 +36,55 @@ func (p *ConnPool) Get(target string) *grpc.ClientConn {
 	return cc
 }
 
+// SetTLSConfig sets the TLS configuration for dialing the target,
+// an empty target sets the default one. Connections are plaintext if not set.
+// The cached connections using the previous configuration are closed and dialed again on next Get,
+// the calls in progress on them are canceled.
+func (p *ConnPool) SetTLSConfig(target string, cfg *tls.Config) {
+	p.tlsConfigs.Store(target, cfg)
+	p.Range(func(key, value interface{}) bool {
+		if _, ok := p.tlsConfigs.Load(key); key == target || target == "" && !ok {
+			p.Delete(key)
+			_ = value.(*grpc.ClientConn).Close()
+		}
+		return true
+	})
+}
+
+func (p *ConnPool) transportCredentials(target string) credentials.TransportCredentials {
+	cfg, ok := p.tlsConfigs.Load(target)
+	if !ok {
+		cfg, ok = p.tlsConfigs.Load("")
+	}
+	if !ok || cfg.(*tls.Config) == nil {
+		return insecure.NewCredentials()
+	}
+	return credentials.NewTLS(cfg.(*tls.Config))
+}
+
+// SetKeyID sets the server key ID for signing the service tokens to the target,
+// an empty target sets the default one. The current key is used if not set.
+func (p *ConnPool) SetKeyID(target string, keyID int64) {
+	p.keyIDs.Store(target, keyID)
+}
+
+func (p *ConnPool) keyID(target string) int64 {
+	keyID, ok := p.keyIDs.Load(target)
+	if !ok {
+		keyID, ok = p.keyIDs.Load("")
+	}
+	if !ok {
+		return credential.CurrentKeyID
+	}
+	return keyID.(int64)
+}
+
 func (p *ConnPool) NewConn(target string) *grpc.ClientConn {
 	// TODO: support more schema
 	cli, err := grpc.Dial(target,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithTransportCredentials(p.transportCredentials(target)),
+		grpc.WithChainUnaryInterceptor(client.UnaryClientInterceptor(p.keyID)),
+		grpc.WithChainStreamInterceptor(client.StreamClientInterceptor(p.keyID)),
 		grpc.WithDefaultServiceConfig(fmt.Sprintf(`{"loadBalancingConfig":[{"%s":{}}]}`, balancer.Implementor().Name())),
 		grpc.WithConnectParams(grpc.ConnectParams{
 			Backoff: backoff.Config{
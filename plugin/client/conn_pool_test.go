@@ -0,0 +1,60 @@
+package client
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/appootb/substratum/v2/client"
+	pb "github.com/appootb/substratum/v2/plugin/balancer"
+	"google.golang.org/grpc/connectivity"
+)
+
+func init() {
+	pb.Init()
+}
+
+func TestConnPool_SetTLSConfig(t *testing.T) {
+	p := &ConnPool{}
+	defer p.Close()
+	p.SetTLSConfig("specific:8080", &tls.Config{})
+	specific := p.Get("specific:8080")
+	other := p.Get("other:8080")
+
+	// The default config resets the connections without a specific config.
+	p.SetTLSConfig("", &tls.Config{})
+	if other.GetState() != connectivity.Shutdown || p.Get("other:8080") == other {
+		t.Fatal("connection of the default config not reset")
+	}
+	if specific.GetState() == connectivity.Shutdown || p.Get("specific:8080") != specific {
+		t.Fatal("connection of a specific config reset")
+	}
+
+	p.SetTLSConfig("specific:8080", nil)
+	if specific.GetState() != connectivity.Shutdown || p.Get("specific:8080") == specific {
+		t.Fatal("connection of the specific config not reset")
+	}
+}
+
+// basicPool implements the connection pool without TLS and key ID settings.
+type basicPool struct {
+	client.ConnPool
+}
+
+func TestSetTLSConfig(t *testing.T) {
+	defer client.RegisterImplementor(client.Implementor())
+
+	p := &ConnPool{}
+	defer p.Close()
+	client.RegisterImplementor(p)
+	if !client.SetTLSConfig("specific:8080", &tls.Config{}) || !client.SetKeyID("specific:8080", 2) {
+		t.Fatal("settings not supported")
+	}
+	if _, ok := p.tlsConfigs.Load("specific:8080"); !ok || p.keyID("specific:8080") != 2 {
+		t.Fatal("settings not applied")
+	}
+
+	client.RegisterImplementor(&basicPool{})
+	if client.SetTLSConfig("specific:8080", &tls.Config{}) || client.SetKeyID("specific:8080", 2) {
+		t.Fatal("settings supported by the basic pool")
+	}
+}
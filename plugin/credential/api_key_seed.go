@@ -0,0 +1,49 @@
+package credential
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/appootb/substratum/v2/credential"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type APIKeySeed struct {
+	sync.Map
+}
+
+func (s *APIKeySeed) Add(key *credential.APIKey) error {
+	k := *key
+	s.Store(k.ID, &k)
+	return nil
+}
+
+func (s *APIKeySeed) Get(keyID int64) (*credential.APIKey, error) {
+	val, ok := s.Load(keyID)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "substratum: api key not found:"+strconv.FormatInt(keyID, 10))
+	}
+	key := val.(*credential.APIKey)
+	if !key.ExpiredAt.IsZero() && time.Now().After(key.ExpiredAt) {
+		return nil, status.Error(codes.Unauthenticated, "substratum: api key expired")
+	}
+	return key, nil
+}
+
+func (s *APIKeySeed) List(uid uint64) ([]*credential.APIKey, error) {
+	var keys []*credential.APIKey
+	s.Range(func(_, v interface{}) bool {
+		if key := v.(*credential.APIKey); key.Account == uid {
+			keys = append(keys, key)
+		}
+		return true
+	})
+	return keys, nil
+}
+
+func (s *APIKeySeed) Revoke(keyID int64) error {
+	s.Delete(keyID)
+	return nil
+}
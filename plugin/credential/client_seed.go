@@ -1,6 +1,7 @@
 package credential
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
 	"sync"
@@ -14,20 +15,51 @@ type clientSeedInfo struct {
 	PrivateKey  []byte
 	NotBefore   time.Time
 	NotAfter    time.Time
+	Locked      bool      // Locked regardless of NotBefore, the lock reason may be empty.
+	LockExpire  time.Time // Expiration of the lock, never expires if zero.
 	LockMessage string
 }
 
+// locked reports whether the account is locked by the seed store.
+func (info *clientSeedInfo) locked() bool {
+	return info.Locked && (info.LockExpire.IsZero() || time.Now().Before(info.LockExpire))
+}
+
 type ClientSeed struct {
 	sync.Map
+
+	// Serializes the mutations, the stored values are replaced instead of updated in place.
+	mu sync.Mutex
 }
 
 func (s *ClientSeed) Add(accountID uint64, keyID int64, val []byte, expire time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.add(accountID, keyID, val, expire)
+	return nil
+}
+
+func (s *ClientSeed) add(accountID uint64, keyID int64, val []byte, expire time.Duration) {
 	key := fmt.Sprintf("%d-%d", accountID, keyID)
 	s.Store(key, &clientSeedInfo{
 		PrivateKey: val,
 		NotAfter:   time.Now().Add(expire),
 	})
-	return nil
+}
+
+func (s *ClientSeed) CompareAndSwap(accountID uint64, keyID int64, old, val []byte, expire time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cur, ok := s.Load(fmt.Sprintf("%d-%d", accountID, keyID))
+	if !ok {
+		return false, nil
+	}
+	info := cur.(*clientSeedInfo)
+	if time.Now().After(info.NotAfter) || !bytes.Equal(info.PrivateKey, old) {
+		return false, nil
+	}
+	s.add(accountID, keyID, val, expire)
+	return true, nil
 }
 
 func (s *ClientSeed) Refresh(accountID uint64, keyID int64, _ time.Duration) ([]byte, error) {
@@ -51,12 +83,16 @@ func (s *ClientSeed) Get(accountID uint64, keyID int64) ([]byte, error) {
 }
 
 func (s *ClientSeed) Revoke(accountID uint64, keyID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	key := fmt.Sprintf("%d-%d", accountID, keyID)
 	s.Delete(key)
 	return nil
 }
 
 func (s *ClientSeed) RevokeAll(accountID uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	key := fmt.Sprintf("%d-", accountID)
 	s.Range(func(k, _ interface{}) bool {
 		if strings.HasPrefix(k.(string), key) {
@@ -71,13 +107,15 @@ func (s *ClientSeed) Lock(accountID uint64, reason string, duration time.Duratio
 	if duration <= 0 {
 		duration = time.Hour * 24 * 365 * 100
 	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	key := fmt.Sprintf("%d-", accountID)
 	s.Range(func(k, v interface{}) bool {
 		if strings.HasPrefix(k.(string), key) {
-			info := v.(*clientSeedInfo)
+			info := *v.(*clientSeedInfo)
 			info.NotBefore = time.Now().Add(duration)
 			info.LockMessage = reason
-			s.Store(k, info)
+			s.Store(k, &info)
 		}
 		return true
 	})
@@ -85,13 +123,15 @@ func (s *ClientSeed) Lock(accountID uint64, reason string, duration time.Duratio
 }
 
 func (s *ClientSeed) Unlock(accountID uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	key := fmt.Sprintf("%d-", accountID)
 	s.Range(func(k, v interface{}) bool {
 		if strings.HasPrefix(k.(string), key) {
-			info := v.(*clientSeedInfo)
+			info := *v.(*clientSeedInfo)
 			info.NotBefore = time.Date(1, 1, 1, 0, 0, 0, 0, time.Local)
 			info.LockMessage = ""
-			s.Store(k, info)
+			s.Store(k, &info)
 		}
 		return true
 	})
@@ -0,0 +1,48 @@
+package credential
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClientSeed_CompareAndSwapRevokeAll(t *testing.T) {
+	s := &ClientSeed{}
+	for i := 0; i < 100; i++ {
+		if err := s.Add(1, 1, []byte("old"), time.Hour); err != nil {
+			t.Fatal(err)
+		}
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = s.CompareAndSwap(1, 1, []byte("old"), []byte("new"), time.Hour)
+		}()
+		if err := s.RevokeAll(1); err != nil {
+			t.Fatal(err)
+		}
+		wg.Wait()
+		if val, err := s.Get(1, 1); err == nil {
+			t.Fatal("revoked key written back", string(val))
+		}
+	}
+}
+
+func TestClientSeedInfo_Locked(t *testing.T) {
+	now := time.Now()
+	for _, c := range []struct {
+		name   string
+		info   clientSeedInfo
+		locked bool
+	}{
+		{"not locked", clientSeedInfo{}, false},
+		{"locked", clientSeedInfo{Locked: true}, true},
+		{"lock not expired", clientSeedInfo{Locked: true, LockExpire: now.Add(time.Hour)}, true},
+		{"lock expired", clientSeedInfo{Locked: true, LockExpire: now.Add(-time.Second)}, false},
+		{"validity window", clientSeedInfo{NotBefore: now.Add(time.Hour)}, false},
+	} {
+		if c.info.locked() != c.locked {
+			t.Fatal(c.name, "expected locked", c.locked)
+		}
+	}
+}
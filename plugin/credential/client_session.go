@@ -0,0 +1,147 @@
+package credential
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/appootb/substratum/v2/credential"
+	ictx "github.com/appootb/substratum/v2/internal/context"
+)
+
+const (
+	// DefaultRevocationTTL is used if the expiration of the revoked session is unknown.
+	DefaultRevocationTTL = time.Hour * 24 * 30
+	// SessionSweepInterval of purging the expired sessions and revocations.
+	SessionSweepInterval = time.Minute
+)
+
+// ClientSession is the in-memory session store of a single process,
+// expired sessions and revocations are swept periodically.
+type ClientSession struct {
+	mu       sync.Mutex
+	sessions map[uint64]map[int64]*credential.Session
+	revoked  map[string]time.Time
+}
+
+func NewClientSession() *ClientSession {
+	s := &ClientSession{
+		sessions: map[uint64]map[int64]*credential.Session{},
+		revoked:  map[string]time.Time{},
+	}
+	go s.checkTTL()
+	return s
+}
+
+func (s *ClientSession) checkTTL() {
+	ticker := time.NewTicker(SessionSweepInterval)
+
+	for {
+		select {
+		case <-ictx.Context.Done():
+			ticker.Stop()
+			return
+
+		case <-ticker.C:
+			s.sweep(time.Now())
+		}
+	}
+}
+
+// sweep removes the sessions and revocations expired before now.
+func (s *ClientSession) sweep(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for uid, sessions := range s.sessions {
+		for keyID, session := range sessions {
+			if now.After(session.ExpiredAt) {
+				delete(sessions, keyID)
+			}
+		}
+		if len(sessions) == 0 {
+			delete(s.sessions, uid)
+		}
+	}
+	for key, expiredAt := range s.revoked {
+		if now.After(expiredAt) {
+			delete(s.revoked, key)
+		}
+	}
+}
+
+func (s *ClientSession) revokedKey(uid uint64, keyID int64) string {
+	return fmt.Sprintf("%d-%d", uid, keyID)
+}
+
+func (s *ClientSession) AddSession(uid uint64, session *credential.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.revoked, s.revokedKey(uid, session.KeyID))
+	if _, ok := s.sessions[uid]; !ok {
+		s.sessions[uid] = map[int64]*credential.Session{}
+	}
+	v := *session
+	s.sessions[uid][session.KeyID] = &v
+	return nil
+}
+
+func (s *ClientSession) RefreshSession(uid uint64, keyID int64, expiredAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if session, ok := s.sessions[uid][keyID]; ok {
+		session.ExpiredAt = expiredAt
+	}
+	return nil
+}
+
+func (s *ClientSession) ListSessions(uid uint64) ([]*credential.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	sessions := make([]*credential.Session, 0, len(s.sessions[uid]))
+	for keyID, session := range s.sessions[uid] {
+		if now.After(session.ExpiredAt) {
+			delete(s.sessions[uid], keyID)
+			continue
+		}
+		v := *session
+		sessions = append(sessions, &v)
+	}
+	return sessions, nil
+}
+
+func (s *ClientSession) RevokeSession(uid uint64, keyID int64) error {
+	s.mu.Lock()
+	refreshID := int64(0)
+	expiredAt := time.Now().Add(DefaultRevocationTTL)
+	if session, ok := s.sessions[uid][keyID]; ok {
+		refreshID = session.RefreshID
+		expiredAt = session.ExpiredAt
+		delete(s.sessions[uid], keyID)
+	}
+	s.revoked[s.revokedKey(uid, keyID)] = expiredAt
+	s.mu.Unlock()
+	return revokeSessionKeys(uid, keyID, refreshID)
+}
+
+// revokeSessionKeys revokes the secret key of the session and the paired refresh token.
+func revokeSessionKeys(uid uint64, keyID, refreshID int64) error {
+	if refreshID != 0 {
+		if err := credential.ClientImplementor().Revoke(uid, refreshID); err != nil {
+			return err
+		}
+	}
+	return credential.ClientImplementor().Revoke(uid, keyID)
+}
+
+func (s *ClientSession) IsRevoked(uid uint64, keyID int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := s.revokedKey(uid, keyID)
+	expiredAt, ok := s.revoked[key]
+	if ok && time.Now().After(expiredAt) {
+		delete(s.revoked, key)
+		return false, nil
+	}
+	return ok, nil
+}
@@ -0,0 +1,25 @@
+package credential
+
+import (
+	"testing"
+	"time"
+
+	"github.com/appootb/substratum/v2/credential"
+)
+
+func TestClientSession_Sweep(t *testing.T) {
+	s := NewClientSession()
+	now := time.Now()
+	_ = s.AddSession(1, &credential.Session{KeyID: 1, ExpiredAt: now.Add(time.Minute)})
+	_ = s.AddSession(1, &credential.Session{KeyID: 2, ExpiredAt: now.Add(time.Hour)})
+	_ = s.AddSession(2, &credential.Session{KeyID: 1, ExpiredAt: now.Add(time.Minute)})
+	s.revoked["1-3"] = now.Add(time.Minute)
+
+	s.sweep(now.Add(time.Minute * 2))
+	if len(s.sessions) != 1 || len(s.sessions[1]) != 1 || s.sessions[1][2] == nil {
+		t.Fatal("expired sessions not swept", s.sessions)
+	}
+	if len(s.revoked) != 0 {
+		t.Fatal("expired revocations not swept", s.revoked)
+	}
+}
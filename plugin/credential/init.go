@@ -9,4 +9,10 @@ func Init() {
 	if credential.ServerImplementor() == nil {
 		credential.RegisterServerImplementor(&ServerSeed{})
 	}
+	if credential.APIKeyImplementor() == nil {
+		credential.RegisterAPIKeyImplementor(&APIKeySeed{})
+	}
+	if credential.SessionImplementor() == nil {
+		credential.RegisterSessionImplementor(NewClientSession())
+	}
 }
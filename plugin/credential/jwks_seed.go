@@ -0,0 +1,145 @@
+package credential
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/appootb/substratum/v2/credential"
+	"github.com/appootb/substratum/v2/logger"
+	"github.com/appootb/substratum/v2/token"
+	"github.com/appootb/substratum/v2/util/jwk"
+)
+
+const (
+	DefaultJWKSRefreshInterval = time.Minute * 10
+	// Minimum interval of fetching the key set for unknown key IDs, or after a failed fetching.
+	minJWKSFetchInterval = time.Second * 10
+)
+
+// JWKSSeed holds the public-only server keys fetched from the JWKS endpoint of a peer component,
+// e.g. `http://auth-service:8089/.well-known/jwks.json`.
+// The key set is cached and refreshed periodically or on unknown key IDs, known keys are returned
+// without waiting for the refreshing. The fetching is rate limited by minJWKSFetchInterval.
+type JWKSSeed struct {
+	PublicSeed
+
+	url      string
+	interval time.Duration
+	client   *http.Client
+
+	mu        sync.Mutex
+	fetchedAt time.Time
+	fetching  chan struct{} // Closed when the fetching in progress completes.
+	// Key IDs fetched from the peer, keys added locally are kept on refreshing.
+	fetched map[int64]bool
+}
+
+func NewJWKSSeed(url string, interval time.Duration) *JWKSSeed {
+	if interval <= 0 {
+		interval = DefaultJWKSRefreshInterval
+	}
+	return &JWKSSeed{
+		url:      url,
+		interval: interval,
+		client:   &http.Client{Timeout: time.Second * 5},
+		fetched:  map[int64]bool{},
+	}
+}
+
+func (s *JWKSSeed) Get(keyID int64) ([]byte, error) {
+	return serverKeyBytes(s.GetKey(keyID))
+}
+
+func (s *JWKSSeed) GetKey(keyID int64) (*credential.ServerKey, error) {
+	if key, err := s.PublicSeed.GetKey(keyID); err == nil {
+		s.mu.Lock()
+		stale := time.Since(s.fetchedAt) > s.interval && s.fetching == nil
+		s.mu.Unlock()
+		if stale {
+			go s.refresh()
+		}
+		return key, nil
+	}
+	s.refresh()
+	return s.PublicSeed.GetKey(keyID)
+}
+
+// refresh fetches the key set without holding the lock, concurrent callers wait for the fetching in progress.
+func (s *JWKSSeed) refresh() {
+	s.mu.Lock()
+	if fetching := s.fetching; fetching != nil {
+		s.mu.Unlock()
+		<-fetching
+		return
+	}
+	if time.Since(s.fetchedAt) < minJWKSFetchInterval {
+		s.mu.Unlock()
+		return
+	}
+	fetching := make(chan struct{})
+	s.fetching, s.fetchedAt = fetching, time.Now()
+	s.mu.Unlock()
+
+	set, err := s.fetch()
+	s.mu.Lock()
+	if err == nil {
+		s.update(set)
+	}
+	s.fetching = nil
+	close(fetching)
+	s.mu.Unlock()
+	if err != nil {
+		logger.Error("substratum jwks fetch failed", logger.Content{
+			"url":   s.url,
+			"error": err.Error(),
+		})
+	}
+}
+
+func (s *JWKSSeed) fetch() (*jwk.Set, error) {
+	resp, err := s.client.Get(s.url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	var set jwk.Set
+	if err = json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+// update the fetched keys, called with the lock held.
+func (s *JWKSSeed) update(set *jwk.Set) {
+	fetched := make(map[int64]bool, len(set.Keys))
+	for _, k := range set.Keys {
+		keyID, err := strconv.ParseInt(k.Kid, 10, 64)
+		if err != nil {
+			continue
+		}
+		pub, err := k.PKIX()
+		if err != nil {
+			continue
+		}
+		s.Store(keyID, &credential.ServerKey{
+			ID:        keyID,
+			Key:       pub,
+			Algorithm: token.AlgorithmByName(k.Alg),
+		})
+		fetched[keyID] = true
+	}
+	// Remove the keys revoked by the peer.
+	for keyID := range s.fetched {
+		if !fetched[keyID] {
+			s.Delete(keyID)
+		}
+	}
+	s.fetched = fetched
+}
@@ -0,0 +1,119 @@
+package credential
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/appootb/substratum/v2/plugin/logger"
+	"github.com/appootb/substratum/v2/util/jwk"
+)
+
+func TestJWKSSeed_Fetch(t *testing.T) {
+	logger.Init()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkix, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := jwk.New("1", pkix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, _ := json.Marshal(&jwk.Set{Keys: []*jwk.Key{key}})
+
+	var (
+		requests int32
+		failed   int32 = 1
+		blocking int32
+		release  = make(chan struct{})
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if atomic.LoadInt32(&blocking) == 1 {
+			<-release
+		}
+		if atomic.LoadInt32(&failed) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+		_, _ = w.Write(buf)
+	}))
+	defer srv.Close()
+
+	// Failed fetching is rate limited.
+	s := NewJWKSSeed(srv.URL, time.Minute)
+	for i := 0; i < 3; i++ {
+		if _, err = s.GetKey(1); err == nil {
+			t.Fatal("key found without the key set")
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatal("unexpected fetching requests", n)
+	}
+
+	// Concurrent lookups share the fetching in progress.
+	atomic.StoreInt32(&failed, 0)
+	atomic.StoreInt32(&requests, 0)
+	s = NewJWKSSeed(srv.URL, time.Minute)
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.GetKey(1)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatal("unexpected fetching requests", n)
+	}
+
+	// Known keys are returned without waiting for refreshing the stale key set.
+	atomic.StoreInt32(&blocking, 1)
+	s.mu.Lock()
+	s.fetchedAt = time.Now().Add(-time.Hour)
+	s.mu.Unlock()
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.GetKey(1)
+		done <- err
+	}()
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("blocked by refreshing")
+	}
+	// Wait for the refreshing in background.
+	for atomic.LoadInt32(&requests) != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	for fetching := true; fetching; time.Sleep(time.Millisecond) {
+		s.mu.Lock()
+		fetching = s.fetching != nil
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,40 @@
+package credential
+
+import "time"
+
+const (
+	DefaultCacheSize = 1024
+	DefaultCacheTTL  = time.Second * 10
+)
+
+type SeedOption func(*SeedOptions)
+
+type SeedOptions struct {
+	// Local LRU cache size.
+	CacheSize int
+	// Local cache expiration, revocations on other nodes take effect after it.
+	CacheTTL time.Duration
+}
+
+func NewSeedOptions(opts ...SeedOption) *SeedOptions {
+	options := &SeedOptions{
+		CacheSize: DefaultCacheSize,
+		CacheTTL:  DefaultCacheTTL,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+func WithCacheSize(size int) SeedOption {
+	return func(options *SeedOptions) {
+		options.CacheSize = size
+	}
+}
+
+func WithCacheTTL(ttl time.Duration) SeedOption {
+	return func(options *SeedOptions) {
+		options.CacheTTL = ttl
+	}
+}
@@ -0,0 +1,72 @@
+package credential
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/appootb/substratum/v2/credential"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PublicSeed holds the public-only server keys for verification,
+// the private part is dropped if a private key added, and the algorithm of the key is kept.
+type PublicSeed struct {
+	sync.Map
+}
+
+func (s *PublicSeed) Add(keyID int64, val []byte) error {
+	return s.AddKey(&credential.ServerKey{
+		ID:  keyID,
+		Key: val,
+	})
+}
+
+func (s *PublicSeed) AddKey(key *credential.ServerKey) error {
+	pub, err := key.PublicKey()
+	if err != nil {
+		return err
+	}
+	k := *key
+	k.Key = pub
+	k.Algorithm = key.KeyAlgorithm()
+	s.Store(k.ID, &k)
+	return nil
+}
+
+func (s *PublicSeed) Get(keyID int64) ([]byte, error) {
+	return serverKeyBytes(s.GetKey(keyID))
+}
+
+func (s *PublicSeed) GetKey(keyID int64) (*credential.ServerKey, error) {
+	val, ok := s.Load(keyID)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "substratum: server key not found:"+strconv.FormatInt(keyID, 10))
+	}
+	return validServerKey(val.(*credential.ServerKey))
+}
+
+// Current is not supported, public keys can't sign tokens.
+func (s *PublicSeed) Current() (*credential.ServerKey, error) {
+	return nil, status.Error(codes.Unimplemented, "substratum: public server keys can't sign")
+}
+
+func (s *PublicSeed) Revoke(keyID int64) error {
+	s.Delete(keyID)
+	return nil
+}
+
+func (s *PublicSeed) Keys() ([]*credential.ServerKey, error) {
+	var (
+		now  = time.Now()
+		keys []*credential.ServerKey
+	)
+	s.Range(func(_, v interface{}) bool {
+		if k := *v.(*credential.ServerKey); !k.Expired(now) {
+			keys = append(keys, &k)
+		}
+		return true
+	})
+	return keys, nil
+}
@@ -0,0 +1,330 @@
+package credential
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/appootb/substratum/v2/credential"
+	"github.com/appootb/substratum/v2/storage"
+	"github.com/appootb/substratum/v2/util/cache"
+	"github.com/go-redis/redis/v8"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	RedisClientSeedPrefix = "substratum:seed:client"
+	RedisClientLockPrefix = "substratum:seed:lock"
+	RedisServerSeedKey    = "substratum:seed:server"
+)
+
+// Replaces the value with the expiration in milliseconds if the current value matches.
+var redisCompareAndSwap = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+	return 1
+end
+return 0`)
+
+// RedisClientSeed stores the client secret keys in the redis of the storage, expired by redis.
+// A key ID index of each user is kept for revoking all the keys.
+type RedisClientSeed struct {
+	storage storage.Storage
+	cache   cache.Cache
+	ttl     time.Duration
+}
+
+func NewRedisClientSeed(s storage.Storage, opts ...SeedOption) *RedisClientSeed {
+	options := NewSeedOptions(opts...)
+	return &RedisClientSeed{
+		storage: s,
+		cache:   cache.New(cache.LRU, cache.WithSize(options.CacheSize)),
+		ttl:     options.CacheTTL,
+	}
+}
+
+func (s *RedisClientSeed) seedKey(uid uint64, keyID int64) string {
+	return fmt.Sprintf("%s:%d:%d", RedisClientSeedPrefix, uid, keyID)
+}
+
+func (s *RedisClientSeed) indexKey(uid uint64) string {
+	return fmt.Sprintf("%s:%d", RedisClientSeedPrefix, uid)
+}
+
+func (s *RedisClientSeed) lockKey(uid uint64) string {
+	return fmt.Sprintf("%s:%d", RedisClientLockPrefix, uid)
+}
+
+func (s *RedisClientSeed) Add(uid uint64, keyID int64, val []byte, expire time.Duration) error {
+	ctx := context.Background()
+	rds := s.storage.GetRedis(uid)
+	if err := rds.Set(ctx, s.seedKey(uid, keyID), val, expire).Err(); err != nil {
+		return err
+	}
+	s.cache.Del(s.seedKey(uid, keyID))
+	return s.index(ctx, uid, keyID, expire)
+}
+
+// index adds the key ID to the index of the user, and extends the index expiration.
+func (s *RedisClientSeed) index(ctx context.Context, uid uint64, keyID int64, expire time.Duration) error {
+	key := s.indexKey(uid)
+	rds := s.storage.GetRedis(uid)
+	if err := rds.SAdd(ctx, key, keyID).Err(); err != nil {
+		return err
+	}
+	ttl, err := rds.PTTL(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	if ttl < expire {
+		return rds.PExpire(ctx, key, expire).Err()
+	}
+	return nil
+}
+
+func (s *RedisClientSeed) CompareAndSwap(uid uint64, keyID int64, old, val []byte, expire time.Duration) (bool, error) {
+	key := s.seedKey(uid, keyID)
+	// PX 0 is rejected by redis, expires in 1ms at least.
+	px := expire.Milliseconds()
+	if px < 1 {
+		px = 1
+	}
+	ok, err := redisCompareAndSwap.Run(context.Background(), s.storage.GetRedis(uid),
+		[]string{key}, old, val, px).Int()
+	s.cache.Del(key)
+	if err != nil {
+		return false, err
+	}
+	return ok == 1, nil
+}
+
+func (s *RedisClientSeed) Refresh(uid uint64, keyID int64, expire time.Duration) ([]byte, error) {
+	ctx := context.Background()
+	ok, err := s.storage.GetRedis(uid).PExpire(ctx, s.seedKey(uid, keyID), expire).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "substratum: client key not found:"+s.seedKey(uid, keyID))
+	}
+	if err = s.index(ctx, uid, keyID, expire); err != nil {
+		return nil, err
+	}
+	return s.Get(uid, keyID)
+}
+
+func (s *RedisClientSeed) Get(uid uint64, keyID int64) ([]byte, error) {
+	key := s.seedKey(uid, keyID)
+	val, err := s.cache.GetOrLoad(key, func(interface{}) (interface{}, time.Duration, error) {
+		return s.load(uid, keyID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	info := val.(*clientSeedInfo)
+	if info.locked() {
+		return nil, status.Error(codes.FailedPrecondition, info.LockMessage)
+	}
+	return info.PrivateKey, nil
+}
+
+func (s *RedisClientSeed) load(uid uint64, keyID int64) (*clientSeedInfo, time.Duration, error) {
+	ctx := context.Background()
+	rds := s.storage.GetRedis(uid)
+	val, err := rds.Get(ctx, s.seedKey(uid, keyID)).Bytes()
+	if storage.IsEmpty(err) {
+		return nil, 0, status.Error(codes.Unauthenticated, "substratum: client key not found:"+s.seedKey(uid, keyID))
+	} else if err != nil {
+		return nil, 0, err
+	}
+	// Never cache the key beyond its expiration.
+	ttl, err := rds.PTTL(ctx, s.seedKey(uid, keyID)).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	if ttl < 0 || ttl > s.ttl {
+		ttl = s.ttl
+	}
+	reason, err := rds.Get(ctx, s.lockKey(uid)).Result()
+	if err != nil && !storage.IsEmpty(err) {
+		return nil, 0, err
+	}
+	return &clientSeedInfo{
+		PrivateKey:  val,
+		Locked:      err == nil,
+		LockMessage: reason,
+	}, ttl, nil
+}
+
+func (s *RedisClientSeed) Revoke(uid uint64, keyID int64) error {
+	ctx := context.Background()
+	rds := s.storage.GetRedis(uid)
+	if err := rds.Del(ctx, s.seedKey(uid, keyID)).Err(); err != nil {
+		return err
+	}
+	s.cache.Del(s.seedKey(uid, keyID))
+	return rds.SRem(ctx, s.indexKey(uid), keyID).Err()
+}
+
+func (s *RedisClientSeed) RevokeAll(uid uint64) error {
+	ctx := context.Background()
+	rds := s.storage.GetRedis(uid)
+	keyIDs, err := rds.SMembers(ctx, s.indexKey(uid)).Result()
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(keyIDs)+1)
+	for _, keyID := range keyIDs {
+		keys = append(keys, fmt.Sprintf("%s:%d:%s", RedisClientSeedPrefix, uid, keyID))
+	}
+	keys = append(keys, s.indexKey(uid))
+	if err = rds.Del(ctx, keys...).Err(); err != nil {
+		return err
+	}
+	s.purge(uid)
+	return nil
+}
+
+func (s *RedisClientSeed) Lock(uid uint64, reason string, duration time.Duration) error {
+	if duration < 0 {
+		duration = 0
+	}
+	if err := s.storage.GetRedis(uid).Set(context.Background(), s.lockKey(uid), reason, duration).Err(); err != nil {
+		return err
+	}
+	s.purge(uid)
+	return nil
+}
+
+func (s *RedisClientSeed) Unlock(uid uint64) error {
+	if err := s.storage.GetRedis(uid).Del(context.Background(), s.lockKey(uid)).Err(); err != nil {
+		return err
+	}
+	s.purge(uid)
+	return nil
+}
+
+// purge the local cache of the user.
+func (s *RedisClientSeed) purge(uid uint64) {
+	prefix := s.indexKey(uid) + ":"
+	for _, key := range s.cache.Keys(true) {
+		if strings.HasPrefix(key.(string), prefix) {
+			s.cache.Del(key)
+		}
+	}
+}
+
+// RedisServerSeed stores the server secret keys in a redis hash of the storage,
+// the values are JSON encoded credential.ServerKey.
+type RedisServerSeed struct {
+	storage storage.Storage
+	cache   cache.Cache
+	ttl     time.Duration
+}
+
+func NewRedisServerSeed(s storage.Storage, opts ...SeedOption) *RedisServerSeed {
+	options := NewSeedOptions(opts...)
+	return &RedisServerSeed{
+		storage: s,
+		cache:   cache.New(cache.LRU, cache.WithSize(options.CacheSize)),
+		ttl:     options.CacheTTL,
+	}
+}
+
+func (s *RedisServerSeed) redis() redis.Cmdable {
+	return s.storage.GetRedis(RedisServerSeedKey)
+}
+
+func (s *RedisServerSeed) Add(keyID int64, key []byte) error {
+	return s.AddKey(&credential.ServerKey{
+		ID:  keyID,
+		Key: key,
+	})
+}
+
+func (s *RedisServerSeed) AddKey(key *credential.ServerKey) error {
+	val, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	if err = s.redis().HSet(context.Background(), RedisServerSeedKey, key.ID, val).Err(); err != nil {
+		return err
+	}
+	s.cache.Del(key.ID)
+	s.cache.Del(RedisServerSeedKey)
+	return nil
+}
+
+func (s *RedisServerSeed) Get(keyID int64) ([]byte, error) {
+	return serverKeyBytes(s.GetKey(keyID))
+}
+
+func (s *RedisServerSeed) GetKey(keyID int64) (*credential.ServerKey, error) {
+	val, err := s.cache.GetOrLoad(keyID, func(interface{}) (interface{}, time.Duration, error) {
+		val, err := s.redis().HGet(context.Background(), RedisServerSeedKey, strconv.FormatInt(keyID, 10)).Bytes()
+		if err != nil {
+			return nil, 0, err
+		}
+		var key credential.ServerKey
+		if err = json.Unmarshal(val, &key); err != nil {
+			return nil, 0, err
+		}
+		return &key, s.ttl, nil
+	})
+	if storage.IsEmpty(err) {
+		return serverKeyNotFound(keyID)
+	} else if err != nil {
+		return nil, err
+	}
+	return validServerKey(val.(*credential.ServerKey))
+}
+
+func (s *RedisServerSeed) Current() (*credential.ServerKey, error) {
+	keys, err := s.Keys()
+	if err != nil {
+		return nil, err
+	}
+	return currentServerKey(keys)
+}
+
+func (s *RedisServerSeed) Revoke(keyID int64) error {
+	if err := s.redis().HDel(context.Background(), RedisServerSeedKey, strconv.FormatInt(keyID, 10)).Err(); err != nil {
+		return err
+	}
+	s.cache.Del(keyID)
+	s.cache.Del(RedisServerSeedKey)
+	return nil
+}
+
+func (s *RedisServerSeed) Keys() ([]*credential.ServerKey, error) {
+	val, err := s.cache.GetOrLoad(RedisServerSeedKey, func(interface{}) (interface{}, time.Duration, error) {
+		values, err := s.redis().HVals(context.Background(), RedisServerSeedKey).Result()
+		if err != nil {
+			return nil, 0, err
+		}
+		keys := make([]credential.ServerKey, 0, len(values))
+		for _, v := range values {
+			var key credential.ServerKey
+			if err = json.Unmarshal([]byte(v), &key); err != nil {
+				return nil, 0, err
+			}
+			keys = append(keys, key)
+		}
+		return keys, s.ttl, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	keys := make([]*credential.ServerKey, 0, len(val.([]credential.ServerKey)))
+	for _, key := range val.([]credential.ServerKey) {
+		if k := key; !k.Expired(now) {
+			keys = append(keys, &k)
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,184 @@
+package credential
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	serrors "github.com/appootb/substratum/v2/errors"
+	"github.com/appootb/substratum/v2/storage"
+	"github.com/go-redis/redis/v8"
+	"google.golang.org/grpc/codes"
+)
+
+// memoryRedis implements the redis commands used by the seeds and sessions, keys never expire.
+type memoryRedis struct {
+	redis.Cmdable
+	kvs    map[string]string
+	sets   map[string]map[string]bool
+	hashes map[string]map[string]string
+	// Number of the EXISTS commands.
+	exists int
+}
+
+func newMemoryRedis() *memoryRedis {
+	return &memoryRedis{
+		kvs:    map[string]string{},
+		sets:   map[string]map[string]bool{},
+		hashes: map[string]map[string]string{},
+	}
+}
+
+func (m *memoryRedis) Exists(_ context.Context, keys ...string) *redis.IntCmd {
+	m.exists++
+	var n int64
+	for _, key := range keys {
+		if _, ok := m.kvs[key]; ok {
+			n++
+		}
+	}
+	return redis.NewIntResult(n, nil)
+}
+
+// EvalSha runs the compare-and-swap script, the PX argument is validated as redis does.
+func (m *memoryRedis) EvalSha(_ context.Context, _ string, keys []string, args ...interface{}) *redis.Cmd {
+	if px := args[2].(int64); px <= 0 {
+		return redis.NewCmdResult(nil, errors.New("ERR invalid expire time in 'set' command"))
+	}
+	if v, ok := m.kvs[keys[0]]; !ok || v != string(args[0].([]byte)) {
+		return redis.NewCmdResult(int64(0), nil)
+	}
+	m.kvs[keys[0]] = string(args[1].([]byte))
+	return redis.NewCmdResult(int64(1), nil)
+}
+
+func (m *memoryRedis) HSet(_ context.Context, key string, values ...interface{}) *redis.IntCmd {
+	if m.hashes[key] == nil {
+		m.hashes[key] = map[string]string{}
+	}
+	for i := 0; i+1 < len(values); i += 2 {
+		m.hashes[key][values[i].(string)] = string(values[i+1].([]byte))
+	}
+	return redis.NewIntResult(int64(len(values)/2), nil)
+}
+
+func (m *memoryRedis) HGet(_ context.Context, key, field string) *redis.StringCmd {
+	if v, ok := m.hashes[key][field]; ok {
+		return redis.NewStringResult(v, nil)
+	}
+	return redis.NewStringResult("", redis.Nil)
+}
+
+func (m *memoryRedis) HDel(_ context.Context, key string, fields ...string) *redis.IntCmd {
+	for _, field := range fields {
+		delete(m.hashes[key], field)
+	}
+	return redis.NewIntResult(int64(len(fields)), nil)
+}
+
+func (m *memoryRedis) Set(_ context.Context, key string, value interface{}, _ time.Duration) *redis.StatusCmd {
+	switch v := value.(type) {
+	case []byte:
+		m.kvs[key] = string(v)
+	default:
+		m.kvs[key] = redis.NewCmdResult(v, nil).String()
+	}
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (m *memoryRedis) Get(_ context.Context, key string) *redis.StringCmd {
+	if v, ok := m.kvs[key]; ok {
+		return redis.NewStringResult(v, nil)
+	}
+	return redis.NewStringResult("", redis.Nil)
+}
+
+func (m *memoryRedis) Del(_ context.Context, keys ...string) *redis.IntCmd {
+	var n int64
+	for _, key := range keys {
+		if _, ok := m.kvs[key]; ok {
+			n++
+		}
+		delete(m.kvs, key)
+		delete(m.sets, key)
+	}
+	return redis.NewIntResult(n, nil)
+}
+
+func (m *memoryRedis) SAdd(_ context.Context, key string, members ...interface{}) *redis.IntCmd {
+	if m.sets[key] == nil {
+		m.sets[key] = map[string]bool{}
+	}
+	for _, member := range members {
+		m.sets[key][redis.NewCmdResult(member, nil).String()] = true
+	}
+	return redis.NewIntResult(int64(len(members)), nil)
+}
+
+func (m *memoryRedis) PTTL(context.Context, string) *redis.DurationCmd {
+	return redis.NewDurationResult(-1, nil)
+}
+
+func (m *memoryRedis) PExpire(context.Context, string, time.Duration) *redis.BoolCmd {
+	return redis.NewBoolResult(true, nil)
+}
+
+type memoryStorage struct {
+	storage.Storage
+	rds *memoryRedis
+}
+
+func (s *memoryStorage) GetRedis(interface{}) redis.Cmdable {
+	return s.rds
+}
+
+func TestRedisClientSeed_Lock(t *testing.T) {
+	seed := NewRedisClientSeed(&memoryStorage{rds: newMemoryRedis()})
+	if err := seed.Add(1, 1, []byte("key"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := seed.Get(1, 1); err != nil {
+		t.Fatal(err)
+	}
+	for _, reason := range []string{"", "banned"} {
+		if err := seed.Lock(1, reason, 0); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := seed.Get(1, 1); serrors.ErrorCode(err) != int32(codes.FailedPrecondition) {
+			t.Fatalf("locked with reason %q, got %v", reason, err)
+		}
+		if err := seed.Unlock(1); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := seed.Get(1, 1); err != nil {
+			t.Fatal("unlocked", err)
+		}
+	}
+}
+
+func TestRedisClientSeed_CompareAndSwap(t *testing.T) {
+	seed := NewRedisClientSeed(&memoryStorage{rds: newMemoryRedis()})
+	if err := seed.Add(1, 1, []byte("key"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		old, val string
+		expire   time.Duration
+		swapped  bool
+	}{
+		{"key", "key1", time.Hour, true},
+		{"key", "key2", time.Hour, false},
+		{"key1", "key2", time.Microsecond, true},
+		{"key2", "key3", 0, true},
+		{"key3", "key4", -time.Second, true},
+	} {
+		swapped, err := seed.CompareAndSwap(1, 1, []byte(c.old), []byte(c.val), c.expire)
+		if err != nil {
+			t.Fatal(c.expire, err)
+		}
+		if swapped != c.swapped {
+			t.Fatalf("swap %s to %s, expected %v", c.old, c.val, c.swapped)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package credential
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/appootb/substratum/v2/credential"
+	"github.com/appootb/substratum/v2/storage"
+	"github.com/appootb/substratum/v2/util/cache"
+)
+
+const (
+	RedisSessionPrefix = "substratum:session"
+	RedisRevokedPrefix = "substratum:revoked"
+)
+
+// RedisSession is the session store shared across nodes, built on the redis clients of the storage.
+// Sessions of a user are stored in a hash, and revoked key IDs are stored as keys expire with the sessions.
+// Revocation checks are cached locally the same as the seeds.
+type RedisSession struct {
+	storage storage.Storage
+	cache   cache.Cache
+	ttl     time.Duration
+}
+
+func NewRedisSession(s storage.Storage, opts ...SeedOption) *RedisSession {
+	options := NewSeedOptions(opts...)
+	return &RedisSession{
+		storage: s,
+		cache:   cache.New(cache.LRU, cache.WithSize(options.CacheSize)),
+		ttl:     options.CacheTTL,
+	}
+}
+
+func (s *RedisSession) sessionKey(uid uint64) string {
+	return fmt.Sprintf("%s:%d", RedisSessionPrefix, uid)
+}
+
+func (s *RedisSession) revokedKey(uid uint64, keyID int64) string {
+	return fmt.Sprintf("%s:%d:%d", RedisRevokedPrefix, uid, keyID)
+}
+
+func (s *RedisSession) AddSession(uid uint64, session *credential.Session) error {
+	val, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	key := s.sessionKey(uid)
+	rds := s.storage.GetRedis(uid)
+	if err = rds.HSet(ctx, key, strconv.FormatInt(session.KeyID, 10), val).Err(); err != nil {
+		return err
+	}
+	if err = rds.Del(ctx, s.revokedKey(uid, session.KeyID)).Err(); err != nil {
+		return err
+	}
+	s.cache.Del(s.revokedKey(uid, session.KeyID))
+	return s.extend(ctx, uid, session.ExpiredAt)
+}
+
+// extend the expiration of the user sessions hash to the latest session expiration.
+func (s *RedisSession) extend(ctx context.Context, uid uint64, expiredAt time.Time) error {
+	key := s.sessionKey(uid)
+	rds := s.storage.GetRedis(uid)
+	ttl, err := rds.PTTL(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	if dur := time.Until(expiredAt); ttl < dur {
+		return rds.PExpire(ctx, key, dur).Err()
+	}
+	return nil
+}
+
+func (s *RedisSession) getSession(ctx context.Context, uid uint64, keyID int64) (*credential.Session, error) {
+	val, err := s.storage.GetRedis(uid).HGet(ctx, s.sessionKey(uid), strconv.FormatInt(keyID, 10)).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var session credential.Session
+	if err = json.Unmarshal(val, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (s *RedisSession) RefreshSession(uid uint64, keyID int64, expiredAt time.Time) error {
+	ctx := context.Background()
+	session, err := s.getSession(ctx, uid, keyID)
+	if err != nil {
+		if storage.IsEmpty(err) {
+			return nil
+		}
+		return err
+	}
+	session.ExpiredAt = expiredAt
+	return s.AddSession(uid, session)
+}
+
+func (s *RedisSession) ListSessions(uid uint64) ([]*credential.Session, error) {
+	ctx := context.Background()
+	key := s.sessionKey(uid)
+	rds := s.storage.GetRedis(uid)
+	vals, err := rds.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	sessions := make([]*credential.Session, 0, len(vals))
+	for field, val := range vals {
+		var session credential.Session
+		if err = json.Unmarshal([]byte(val), &session); err != nil || now.After(session.ExpiredAt) {
+			rds.HDel(ctx, key, field)
+			continue
+		}
+		sessions = append(sessions, &session)
+	}
+	return sessions, nil
+}
+
+func (s *RedisSession) RevokeSession(uid uint64, keyID int64) error {
+	ctx := context.Background()
+	rds := s.storage.GetRedis(uid)
+	ttl := DefaultRevocationTTL
+	refreshID := int64(0)
+	session, err := s.getSession(ctx, uid, keyID)
+	if err == nil {
+		ttl = time.Until(session.ExpiredAt)
+		refreshID = session.RefreshID
+	} else if !storage.IsEmpty(err) {
+		return err
+	}
+	if err = rds.HDel(ctx, s.sessionKey(uid), strconv.FormatInt(keyID, 10)).Err(); err != nil {
+		return err
+	}
+	if ttl > 0 {
+		if err = rds.Set(ctx, s.revokedKey(uid, keyID), 1, ttl).Err(); err != nil {
+			return err
+		}
+	}
+	s.cache.Del(s.revokedKey(uid, keyID))
+	return revokeSessionKeys(uid, keyID, refreshID)
+}
+
+func (s *RedisSession) IsRevoked(uid uint64, keyID int64) (bool, error) {
+	key := s.revokedKey(uid, keyID)
+	val, err := s.cache.GetOrLoad(key, func(interface{}) (interface{}, time.Duration, error) {
+		n, err := s.storage.GetRedis(uid).Exists(context.Background(), key).Result()
+		if err != nil {
+			return nil, 0, err
+		}
+		return n > 0, s.ttl, nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return val.(bool), nil
+}
@@ -0,0 +1,60 @@
+package credential
+
+import (
+	"testing"
+	"time"
+
+	"github.com/appootb/substratum/v2/credential"
+)
+
+func TestSessionStore_Revoke(t *testing.T) {
+	Init()
+	rds := newMemoryRedis()
+	for name, store := range map[string]credential.SessionStore{
+		"memory": NewClientSession(),
+		"redis":  NewRedisSession(&memoryStorage{rds: rds}),
+	} {
+		uid := uint64(len(name))
+		for _, keyID := range []int64{1, -1} {
+			if err := credential.ClientImplementor().Add(uid, keyID, []byte("key"), time.Hour); err != nil {
+				t.Fatal(err)
+			}
+		}
+		session := &credential.Session{KeyID: 1, RefreshID: -1, ExpiredAt: time.Now().Add(time.Hour)}
+		if err := store.AddSession(uid, session); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.RevokeSession(uid, 1); err != nil {
+			t.Fatal(err)
+		}
+		if revoked, err := store.IsRevoked(uid, 1); err != nil || !revoked {
+			t.Fatal(name, "session not revoked", err)
+		}
+		for _, keyID := range []int64{1, -1} {
+			if _, err := credential.ClientImplementor().Get(uid, keyID); err == nil {
+				t.Fatal(name, "key not revoked", keyID)
+			}
+		}
+
+		// Signed in again with the same key ID.
+		if err := store.AddSession(uid, session); err != nil {
+			t.Fatal(err)
+		}
+		if revoked, err := store.IsRevoked(uid, 1); err != nil || revoked {
+			t.Fatal(name, "session still revoked", err)
+		}
+	}
+}
+
+func TestRedisSession_Cache(t *testing.T) {
+	rds := newMemoryRedis()
+	store := NewRedisSession(&memoryStorage{rds: rds})
+	for i := 0; i < 3; i++ {
+		if revoked, err := store.IsRevoked(1, 1); err != nil || revoked {
+			t.Fatal("unexpected revocation", err)
+		}
+	}
+	if rds.exists != 1 {
+		t.Fatal("revocation not cached, EXISTS called", rds.exists)
+	}
+}
@@ -1,26 +1,121 @@
 package credential
 
-import "sync"
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/appootb/substratum/v2/credential"
+	"github.com/appootb/substratum/v2/metadata"
+	"github.com/appootb/substratum/v2/proto/go/secret"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Default secret seed of unknown server key IDs, for develop usage only.
+var defaultServerKey = []byte("1d011a3a57f9d3fa38541713ae03c6a238233bd2")
+
+// serverKeyNotFound returns the default server key if metadata.EnvDevelop is set.
+func serverKeyNotFound(keyID int64) (*credential.ServerKey, error) {
+	if metadata.EnvDevelop != "" {
+		return &credential.ServerKey{
+			ID:        keyID,
+			Key:       defaultServerKey,
+			Algorithm: secret.Algorithm_HMAC,
+		}, nil
+	}
+	return nil, status.Error(codes.Unauthenticated, "substratum: server key not found:"+strconv.FormatInt(keyID, 10))
+}
+
+// validServerKey returns a copy of the secret key if it is within the validity window.
+func validServerKey(key *credential.ServerKey) (*credential.ServerKey, error) {
+	now := time.Now()
+	if now.Before(key.NotBefore) {
+		return nil, status.Error(codes.Unauthenticated, "substratum: server key not valid yet:"+strconv.FormatInt(key.ID, 10))
+	}
+	if key.Expired(now) {
+		return nil, status.Error(codes.Unauthenticated, "substratum: server key expired:"+strconv.FormatInt(key.ID, 10))
+	}
+	k := *key
+	return &k, nil
+}
+
+// serverKeyBytes returns the secret key of the server key.
+func serverKeyBytes(key *credential.ServerKey, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	return key.Key, nil
+}
+
+// currentServerKey returns the current signing key of the keys,
+// the default server key is used if metadata.EnvDevelop is set.
+func currentServerKey(keys []*credential.ServerKey) (*credential.ServerKey, error) {
+	if key := credential.CurrentKey(keys, time.Now()); key != nil {
+		return key, nil
+	}
+	if metadata.EnvDevelop != "" {
+		return &credential.ServerKey{
+			ID:        credential.CurrentKeyID,
+			Key:       defaultServerKey,
+			Algorithm: secret.Algorithm_HMAC,
+		}, nil
+	}
+	return nil, status.Error(codes.Unauthenticated, "substratum: no valid server key")
+}
 
 type ServerSeed struct {
 	sync.Map
 }
 
 func (s *ServerSeed) Add(keyID int64, val []byte) error {
-	s.Store(keyID, val)
+	return s.AddKey(&credential.ServerKey{
+		ID:  keyID,
+		Key: val,
+	})
+}
+
+func (s *ServerSeed) AddKey(key *credential.ServerKey) error {
+	k := *key
+	s.Store(k.ID, &k)
 	return nil
 }
 
 func (s *ServerSeed) Get(keyID int64) ([]byte, error) {
+	return serverKeyBytes(s.GetKey(keyID))
+}
+
+func (s *ServerSeed) GetKey(keyID int64) (*credential.ServerKey, error) {
 	val, ok := s.Load(keyID)
 	if !ok {
-		// Default secret seed, for develop usage
-		return []byte("1d011a3a57f9d3fa38541713ae03c6a238233bd2"), nil
+		return serverKeyNotFound(keyID)
+	}
+	return validServerKey(val.(*credential.ServerKey))
+}
+
+func (s *ServerSeed) Current() (*credential.ServerKey, error) {
+	keys, err := s.Keys()
+	if err != nil {
+		return nil, err
 	}
-	return val.([]byte), nil
+	return currentServerKey(keys)
 }
 
 func (s *ServerSeed) Revoke(keyID int64) error {
 	s.Delete(keyID)
 	return nil
 }
+
+func (s *ServerSeed) Keys() ([]*credential.ServerKey, error) {
+	var (
+		now  = time.Now()
+		keys []*credential.ServerKey
+	)
+	s.Range(func(_, v interface{}) bool {
+		if k := *v.(*credential.ServerKey); !k.Expired(now) {
+			keys = append(keys, &k)
+		}
+		return true
+	})
+	return keys, nil
+}
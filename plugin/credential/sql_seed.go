@@ -0,0 +1,342 @@
+package credential
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/appootb/substratum/v2/credential"
+	"github.com/appootb/substratum/v2/proto/go/secret"
+	"github.com/appootb/substratum/v2/storage"
+	"github.com/appootb/substratum/v2/util/cache"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type sqlClientSeed struct {
+	Account   uint64    `gorm:"primaryKey;autoIncrement:false"`
+	KeyID     int64     `gorm:"primaryKey;autoIncrement:false"`
+	Key       []byte    `gorm:"not null"`
+	ExpiredAt time.Time `gorm:"not null; index:idx_expired_at"`
+}
+
+func (sqlClientSeed) TableName() string {
+	return "substratum_client_seed"
+}
+
+type sqlClientLock struct {
+	Account   uint64    `gorm:"primaryKey;autoIncrement:false"`
+	Reason    string    `gorm:"not null"`
+	ExpiredAt time.Time `gorm:"not null"`
+}
+
+func (sqlClientLock) TableName() string {
+	return "substratum_client_lock"
+}
+
+type sqlServerSeed struct {
+	KeyID     int64            `gorm:"primaryKey;autoIncrement:false"`
+	Key       []byte           `gorm:"not null"`
+	Algorithm secret.Algorithm `gorm:"not null;default:0"`
+	NotBefore *time.Time
+	NotAfter  *time.Time `gorm:"index:idx_not_after"`
+}
+
+func newSQLServerSeed(key *credential.ServerKey) *sqlServerSeed {
+	seed := &sqlServerSeed{
+		KeyID:     key.ID,
+		Key:       key.Key,
+		Algorithm: key.Algorithm,
+	}
+	if !key.NotBefore.IsZero() {
+		seed.NotBefore = &key.NotBefore
+	}
+	if !key.NotAfter.IsZero() {
+		seed.NotAfter = &key.NotAfter
+	}
+	return seed
+}
+
+func (m *sqlServerSeed) ServerKey() *credential.ServerKey {
+	key := &credential.ServerKey{
+		ID:        m.KeyID,
+		Key:       m.Key,
+		Algorithm: m.Algorithm,
+	}
+	if m.NotBefore != nil {
+		key.NotBefore = *m.NotBefore
+	}
+	if m.NotAfter != nil {
+		key.NotAfter = *m.NotAfter
+	}
+	return key
+}
+
+func (sqlServerSeed) TableName() string {
+	return "substratum_server_seed"
+}
+
+// SQLClientSeed stores the client secret keys in the database of the storage,
+// expired keys are filtered out and purged when adding new keys of the account.
+type SQLClientSeed struct {
+	storage storage.Storage
+	cache   cache.Cache
+	ttl     time.Duration
+}
+
+func NewSQLClientSeed(s storage.Storage, opts ...SeedOption) (*SQLClientSeed, error) {
+	if err := s.GetDB().AutoMigrate(&sqlClientSeed{}, &sqlClientLock{}); err != nil {
+		return nil, err
+	}
+	options := NewSeedOptions(opts...)
+	return &SQLClientSeed{
+		storage: s,
+		cache:   cache.New(cache.LRU, cache.WithSize(options.CacheSize)),
+		ttl:     options.CacheTTL,
+	}, nil
+}
+
+func (s *SQLClientSeed) cacheKey(uid uint64, keyID int64) string {
+	return fmt.Sprintf("%d-%d", uid, keyID)
+}
+
+func (s *SQLClientSeed) Add(uid uint64, keyID int64, val []byte, expire time.Duration) error {
+	now := time.Now()
+	err := s.storage.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("account = ? AND expired_at <= ?", uid, now).Delete(&sqlClientSeed{}).Error; err != nil {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&sqlClientSeed{
+			Account:   uid,
+			KeyID:     keyID,
+			Key:       val,
+			ExpiredAt: now.Add(expire),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+	s.cache.Del(s.cacheKey(uid, keyID))
+	return nil
+}
+
+func (s *SQLClientSeed) CompareAndSwap(uid uint64, keyID int64, old, val []byte, expire time.Duration) (bool, error) {
+	now := time.Now()
+	result := s.storage.GetDB().Model(&sqlClientSeed{}).
+		Where(&sqlClientSeed{Account: uid, KeyID: keyID, Key: old}).
+		Where("expired_at > ?", now).
+		Updates(&sqlClientSeed{Key: val, ExpiredAt: now.Add(expire)})
+	s.cache.Del(s.cacheKey(uid, keyID))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (s *SQLClientSeed) Refresh(uid uint64, keyID int64, expire time.Duration) ([]byte, error) {
+	now := time.Now()
+	result := s.storage.GetDB().Model(&sqlClientSeed{}).
+		Where("account = ? AND key_id = ? AND expired_at > ?", uid, keyID, now).
+		Update("expired_at", now.Add(expire))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, status.Error(codes.Unauthenticated, "substratum: client key not found:"+s.cacheKey(uid, keyID))
+	}
+	return s.Get(uid, keyID)
+}
+
+func (s *SQLClientSeed) Get(uid uint64, keyID int64) ([]byte, error) {
+	val, err := s.cache.GetOrLoad(s.cacheKey(uid, keyID), func(interface{}) (interface{}, time.Duration, error) {
+		return s.load(uid, keyID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	info := val.(*clientSeedInfo)
+	if time.Now().After(info.NotAfter) {
+		return nil, status.Error(codes.Unauthenticated, "substratum: client key expired")
+	}
+	if info.locked() {
+		return nil, status.Error(codes.FailedPrecondition, info.LockMessage)
+	}
+	return info.PrivateKey, nil
+}
+
+func (s *SQLClientSeed) load(uid uint64, keyID int64) (*clientSeedInfo, time.Duration, error) {
+	var (
+		now  = time.Now()
+		seed sqlClientSeed
+		lock sqlClientLock
+	)
+	db := s.storage.GetDB()
+	err := db.Where("account = ? AND key_id = ? AND expired_at > ?", uid, keyID, now).Take(&seed).Error
+	if storage.IsEmpty(err) {
+		return nil, 0, status.Error(codes.Unauthenticated, "substratum: client key not found:"+s.cacheKey(uid, keyID))
+	} else if err != nil {
+		return nil, 0, err
+	}
+	err = db.Where("account = ? AND expired_at > ?", uid, now).Take(&lock).Error
+	if err != nil && !storage.IsEmpty(err) {
+		return nil, 0, err
+	}
+	return &clientSeedInfo{
+		PrivateKey:  seed.Key,
+		NotAfter:    seed.ExpiredAt,
+		Locked:      err == nil,
+		LockExpire:  lock.ExpiredAt,
+		LockMessage: lock.Reason,
+	}, s.ttl, nil
+}
+
+func (s *SQLClientSeed) Revoke(uid uint64, keyID int64) error {
+	err := s.storage.GetDB().Where("account = ? AND key_id = ?", uid, keyID).Delete(&sqlClientSeed{}).Error
+	if err != nil {
+		return err
+	}
+	s.cache.Del(s.cacheKey(uid, keyID))
+	return nil
+}
+
+func (s *SQLClientSeed) RevokeAll(uid uint64) error {
+	if err := s.storage.GetDB().Where("account = ?", uid).Delete(&sqlClientSeed{}).Error; err != nil {
+		return err
+	}
+	s.purge(uid)
+	return nil
+}
+
+func (s *SQLClientSeed) Lock(uid uint64, reason string, duration time.Duration) error {
+	if duration <= 0 {
+		duration = time.Hour * 24 * 365 * 100
+	}
+	err := s.storage.GetDB().Clauses(clause.OnConflict{UpdateAll: true}).Create(&sqlClientLock{
+		Account:   uid,
+		Reason:    reason,
+		ExpiredAt: time.Now().Add(duration),
+	}).Error
+	if err != nil {
+		return err
+	}
+	s.purge(uid)
+	return nil
+}
+
+func (s *SQLClientSeed) Unlock(uid uint64) error {
+	if err := s.storage.GetDB().Where("account = ?", uid).Delete(&sqlClientLock{}).Error; err != nil {
+		return err
+	}
+	s.purge(uid)
+	return nil
+}
+
+// purge the local cache of the user.
+func (s *SQLClientSeed) purge(uid uint64) {
+	prefix := fmt.Sprintf("%d-", uid)
+	for _, key := range s.cache.Keys(true) {
+		if strings.HasPrefix(key.(string), prefix) {
+			s.cache.Del(key)
+		}
+	}
+}
+
+// SQLServerSeed stores the server secret keys in the database of the storage.
+type SQLServerSeed struct {
+	storage storage.Storage
+	cache   cache.Cache
+	ttl     time.Duration
+}
+
+func NewSQLServerSeed(s storage.Storage, opts ...SeedOption) (*SQLServerSeed, error) {
+	if err := s.GetDB().AutoMigrate(&sqlServerSeed{}); err != nil {
+		return nil, err
+	}
+	options := NewSeedOptions(opts...)
+	return &SQLServerSeed{
+		storage: s,
+		cache:   cache.New(cache.LRU, cache.WithSize(options.CacheSize)),
+		ttl:     options.CacheTTL,
+	}, nil
+}
+
+func (s *SQLServerSeed) Add(keyID int64, key []byte) error {
+	return s.AddKey(&credential.ServerKey{
+		ID:  keyID,
+		Key: key,
+	})
+}
+
+func (s *SQLServerSeed) AddKey(key *credential.ServerKey) error {
+	err := s.storage.GetDB().Clauses(clause.OnConflict{UpdateAll: true}).Create(newSQLServerSeed(key)).Error
+	if err != nil {
+		return err
+	}
+	s.cache.Del(key.ID)
+	s.cache.Del(sqlServerSeed{}.TableName())
+	return nil
+}
+
+func (s *SQLServerSeed) Get(keyID int64) ([]byte, error) {
+	return serverKeyBytes(s.GetKey(keyID))
+}
+
+func (s *SQLServerSeed) GetKey(keyID int64) (*credential.ServerKey, error) {
+	val, err := s.cache.GetOrLoad(keyID, func(interface{}) (interface{}, time.Duration, error) {
+		var seed sqlServerSeed
+		if err := s.storage.GetDB().Where("key_id = ?", keyID).Take(&seed).Error; err != nil {
+			return nil, 0, err
+		}
+		return seed.ServerKey(), s.ttl, nil
+	})
+	if storage.IsEmpty(err) {
+		return serverKeyNotFound(keyID)
+	} else if err != nil {
+		return nil, err
+	}
+	return validServerKey(val.(*credential.ServerKey))
+}
+
+func (s *SQLServerSeed) Current() (*credential.ServerKey, error) {
+	keys, err := s.Keys()
+	if err != nil {
+		return nil, err
+	}
+	return currentServerKey(keys)
+}
+
+func (s *SQLServerSeed) Revoke(keyID int64) error {
+	if err := s.storage.GetDB().Where("key_id = ?", keyID).Delete(&sqlServerSeed{}).Error; err != nil {
+		return err
+	}
+	s.cache.Del(keyID)
+	s.cache.Del(sqlServerSeed{}.TableName())
+	return nil
+}
+
+func (s *SQLServerSeed) Keys() ([]*credential.ServerKey, error) {
+	val, err := s.cache.GetOrLoad(sqlServerSeed{}.TableName(), func(interface{}) (interface{}, time.Duration, error) {
+		var seeds []*sqlServerSeed
+		if err := s.storage.GetDB().Find(&seeds).Error; err != nil {
+			return nil, 0, err
+		}
+		keys := make([]credential.ServerKey, 0, len(seeds))
+		for _, seed := range seeds {
+			keys = append(keys, *seed.ServerKey())
+		}
+		return keys, s.ttl, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	keys := make([]*credential.ServerKey, 0, len(val.([]credential.ServerKey)))
+	for _, key := range val.([]credential.ServerKey) {
+		if k := key; !k.Expired(now) {
+			keys = append(keys, &k)
+		}
+	}
+	return keys, nil
+}
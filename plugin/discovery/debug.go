@@ -142,6 +142,48 @@ func (m *Debug) KeepAlive(key, value string, ttl time.Duration) error {
 	return m.Set(key, value, 0)
 }
 
+// Create sets value and keeps alive with the ttl for the specified key only if the key does not exist.
+func (m *Debug) Create(key, value string, _ time.Duration) (bool, error) {
+	m.Lock()
+	if _, ok := m.kvs[key]; ok {
+		m.Unlock()
+		return false, nil
+	}
+	m.kvs[key] = &node{
+		k:      key,
+		v:      value,
+		expire: zeroTime,
+	}
+	m.Unlock()
+	m.event <- &discovery.WatchEvent{
+		EventType: discovery.Update,
+		KVPair: discovery.KVPair{
+			Key:   key,
+			Value: value,
+		},
+	}
+	return true, nil
+}
+
+// Delete the specified key and stop keeping it alive.
+func (m *Debug) Delete(key string) error {
+	m.Lock()
+	n, ok := m.kvs[key]
+	delete(m.kvs, key)
+	m.Unlock()
+	if !ok {
+		return nil
+	}
+	m.event <- &discovery.WatchEvent{
+		EventType: discovery.Delete,
+		KVPair: discovery.KVPair{
+			Key:   n.k,
+			Value: n.v,
+		},
+	}
+	return nil
+}
+
 // Close the provider connection.
 func (m *Debug) Close() {}
 
@@ -99,6 +99,24 @@ func (m *Discovery) Register(component, addr string, opts ...discovery.Option) (
 	return info.UniqueID, nil
 }
 
+// Deregister removes the rpc address registered for the component.
+func (m *Discovery) Deregister(component string) error {
+	v, ok := m.lc.Load(component)
+	if !ok {
+		return nil
+	}
+	info := v.(*NodeInfo)
+	nodeKey := fmt.Sprintf("%s/%s/%s", ServicePrefix, component, info.Address)
+	// The node key expires with the ttl if the backend is not able to delete it.
+	if deleter, ok := discovery.BackendImplementor().(discovery.Deleter); ok {
+		if err := deleter.Delete(nodeKey); err != nil {
+			return err
+		}
+	}
+	m.lc.Delete(component)
+	return nil
+}
+
 // GetAddresses returns rpc service addresses.
 func (m *Discovery) GetAddresses(service string) []resolver.Address {
 	parts := strings.Split(service, ".")
@@ -1,6 +1,9 @@
 package queue
 
 import (
+	"context"
+	"sync"
+
 	sctx "github.com/appootb/substratum/v2/context"
 	ictx "github.com/appootb/substratum/v2/internal/context"
 	"github.com/appootb/substratum/v2/logger"
@@ -26,11 +29,23 @@ func Init() {
 		queue.RegisterBackendImplementor(&Debug{})
 	}
 	if queue.Implementor() == nil {
-		queue.RegisterImplementor(&Queue{})
+		queue.RegisterImplementor(newQueue())
 	}
 }
 
-type Queue struct{}
+func newQueue() queue.Queue {
+	ctx, cancel := context.WithCancel(ictx.Context)
+	return &Queue{
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+type Queue struct {
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
 
 // Publish writes a message body to the specified topic.
 func (m *Queue) Publish(topic string, content []byte, opts ...queue.PublishOption) error {
@@ -58,12 +73,31 @@ func (m *Queue) Subscribe(topic string, handler queue.Consumer, opts ...queue.Su
 		return err
 	}
 	for i := 0; i < options.Concurrency; i++ {
+		m.wg.Add(1)
 		go m.process(topic, messageChan, handler, options)
 	}
 	return nil
 }
 
+// Shutdown stops consuming and waits for the in-flight messages to be processed.
+func (m *Queue) Shutdown(ctx context.Context) error {
+	m.cancel()
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (m *Queue) process(topic string, ch <-chan queue.MessageWrapper, h queue.Consumer, opts *queue.SubscribeOptions) {
+	defer m.wg.Done()
+
 	for {
 		var (
 			err    error
@@ -72,7 +106,7 @@ func (m *Queue) process(topic string, ch <-chan queue.MessageWrapper, h queue.Co
 		)
 
 		select {
-		case <-ictx.Context.Done():
+		case <-m.ctx.Done():
 			return
 		case msg = <-ch:
 		}
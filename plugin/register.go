@@ -13,6 +13,7 @@ import (
 	"github.com/appootb/substratum/v2/plugin/logger"
 	"github.com/appootb/substratum/v2/plugin/queue"
 	"github.com/appootb/substratum/v2/plugin/resolver"
+	"github.com/appootb/substratum/v2/plugin/snowflake"
 	"github.com/appootb/substratum/v2/plugin/storage"
 	"github.com/appootb/substratum/v2/plugin/task"
 	"github.com/appootb/substratum/v2/plugin/token"
@@ -36,6 +37,8 @@ func Register() {
 		discovery.Init()
 		// Resolver
 		resolver.Init()
+		// Snowflake
+		snowflake.Init()
 		// Errors
 		errors.Init()
 		// Logger
@@ -0,0 +1,157 @@
+package snowflake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/appootb/substratum/v2/discovery"
+	"github.com/appootb/substratum/v2/logger"
+	sf "github.com/appootb/substratum/v2/util/snowflake"
+)
+
+var (
+	ErrNoFreePartition = errors.New("substratum: no free snowflake partition")
+)
+
+// lease holds a free partition slot through the discovery backend.
+//
+// Free slots are claimed by create-if-absent if the backend implements discovery.Creator,
+// otherwise concurrent claims on the same slot are detected by reading the owner back.
+// The loser skips the slot, or stops generating IDs until a new slot is acquired
+// if the lease is lost afterwards.
+type lease struct {
+	prefix string
+	owner  string
+	ttl    time.Duration
+	flake  *sf.Snowflake
+
+	key       atomic.Value
+	partition int64
+	verified  int64 // Unix nano of the last ownership verification.
+}
+
+// Valid implements sf.Lease.
+func (l *lease) Valid() bool {
+	verified := atomic.LoadInt64(&l.verified)
+	return verified > 0 && time.Since(time.Unix(0, verified)) < l.ttl
+}
+
+func (l *lease) acquire() error {
+	backend := discovery.BackendImplementor()
+	// Spread the start slot of the nodes.
+	hint, err := backend.Incr(l.prefix)
+	if err != nil {
+		return err
+	}
+	pairs, err := backend.Get(l.prefix+"/", true)
+	if err != nil {
+		return err
+	}
+	used := make(map[string]bool, len(pairs.KVs))
+	for _, kv := range pairs.KVs {
+		used[kv.Key] = true
+	}
+	mask := l.flake.Layout().PartitionMask()
+	for i := int64(0); i <= mask; i++ {
+		partition := (hint + i) & mask
+		key := fmt.Sprintf("%s/%d", l.prefix, partition)
+		if used[key] {
+			continue
+		}
+		// Collision detection, the slot might be claimed concurrently.
+		if claimed, err := l.claim(backend, key); err != nil {
+			return err
+		} else if !claimed {
+			continue
+		}
+		l.key.Store(key)
+		atomic.StoreInt64(&l.partition, partition)
+		l.flake.SetPartitionID(partition)
+		atomic.StoreInt64(&l.verified, time.Now().UnixNano())
+		return nil
+	}
+	return ErrNoFreePartition
+}
+
+// claim the free slot, returns false if the slot is claimed by another node.
+func (l *lease) claim(backend discovery.Backend, key string) (bool, error) {
+	if creator, ok := backend.(discovery.Creator); ok {
+		return creator.Create(key, l.owner, l.ttl)
+	}
+	if err := backend.KeepAlive(key, l.owner, l.ttl); err != nil {
+		return false, err
+	}
+	return l.isOwner(key)
+}
+
+func (l *lease) isOwner(key string) (bool, error) {
+	pairs, err := discovery.BackendImplementor().Get(key, false)
+	if err != nil {
+		return false, err
+	}
+	return len(pairs.KVs) == 1 && pairs.KVs[0].Value == l.owner, nil
+}
+
+func (l *lease) verify() {
+	key, _ := l.key.Load().(string)
+	if key != "" {
+		owned, err := l.isOwner(key)
+		if err != nil {
+			// Unable to verify, expires after the ttl.
+			logger.Warn("substratum snowflake lease verify failed", logger.Content{
+				"key":   key,
+				"error": err.Error(),
+			})
+			return
+		}
+		if owned {
+			atomic.StoreInt64(&l.verified, time.Now().UnixNano())
+			return
+		}
+		atomic.StoreInt64(&l.verified, 0)
+		l.key.Store("")
+		logger.Error("substratum snowflake lease lost", logger.Content{
+			"key": key,
+		})
+	}
+	// Lease lost, try to acquire a new slot.
+	if err := l.acquire(); err != nil {
+		logger.Error("substratum snowflake lease acquire failed", logger.Content{
+			"prefix": l.prefix,
+			"error":  err.Error(),
+		})
+	}
+}
+
+func (l *lease) keepAlive(ctx context.Context) {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.verify()
+		}
+	}
+}
+
+func (l *lease) release() error {
+	atomic.StoreInt64(&l.verified, 0)
+	key, _ := l.key.Load().(string)
+	if key == "" {
+		return nil
+	}
+	if owned, err := l.isOwner(key); err != nil || !owned {
+		return err
+	}
+	// The key expires with the ttl if the backend is not able to delete it.
+	if deleter, ok := discovery.BackendImplementor().(discovery.Deleter); ok {
+		return deleter.Delete(key)
+	}
+	return nil
+}
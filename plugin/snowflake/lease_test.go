@@ -0,0 +1,178 @@
+package snowflake
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/appootb/substratum/v2/discovery"
+	pd "github.com/appootb/substratum/v2/plugin/discovery"
+	"github.com/appootb/substratum/v2/plugin/logger"
+	sf "github.com/appootb/substratum/v2/util/snowflake"
+)
+
+func init() {
+	logger.Init()
+	pd.Init()
+}
+
+// Two partition slots.
+var testLayout = sf.Layout{
+	TimestampBits: 50,
+	PartitionBits: 1,
+	SequenceBits:  12,
+	TimeUnit:      time.Millisecond,
+}
+
+func newTestLease(prefix, owner string) *lease {
+	l := &lease{
+		prefix: fmt.Sprintf("%s/%s", PartitionPrefix, prefix),
+		owner:  owner,
+		ttl:    time.Minute,
+		flake:  sf.New(sf.WithLayout(testLayout)),
+	}
+	l.flake.SetLease(l)
+	return l
+}
+
+// racingBackend overwrites the owner of the key after keeping it alive,
+// as claimed by another node concurrently.
+type racingBackend struct {
+	discovery.Backend
+	key string
+}
+
+func (b *racingBackend) KeepAlive(key, value string, ttl time.Duration) error {
+	if err := b.Backend.KeepAlive(key, value, ttl); err != nil {
+		return err
+	}
+	if key == b.key {
+		return b.Backend.Set(key, "racer", 0)
+	}
+	return nil
+}
+
+// claimingBackend creates the key for another node just before creating it,
+// as claimed by another node concurrently.
+type claimingBackend struct {
+	discovery.Backend
+	key string
+}
+
+func (b *claimingBackend) Create(key, value string, ttl time.Duration) (bool, error) {
+	creator := b.Backend.(discovery.Creator)
+	if key == b.key {
+		if _, err := creator.Create(key, "racer", ttl); err != nil {
+			return false, err
+		}
+	}
+	return creator.Create(key, value, ttl)
+}
+
+func TestLease_Acquire(t *testing.T) {
+	a := newTestLease("acquire", "a")
+	b := newTestLease("acquire", "b")
+	if err := a.acquire(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.acquire(); err != nil {
+		t.Fatal(err)
+	}
+	if a.partition == b.partition {
+		t.Fatal("partition shared", a.partition)
+	}
+	if !a.Valid() || !b.Valid() {
+		t.Fatal("lease not valid")
+	}
+	if id, err := a.flake.Next(); err != nil || a.flake.PartitionID(id) != uint64(a.partition) {
+		t.Fatal("unexpected partition of id", id, err)
+	}
+	// No free slot.
+	if err := newTestLease("acquire", "c").acquire(); err != ErrNoFreePartition {
+		t.Fatal("expected no free partition, got", err)
+	}
+	// Released slot is free again.
+	if err := a.release(); err != nil {
+		t.Fatal(err)
+	}
+	if a.Valid() {
+		t.Fatal("released lease valid")
+	}
+	if err := newTestLease("acquire", "c").acquire(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLease_Collision(t *testing.T) {
+	backend := discovery.BackendImplementor()
+	defer discovery.RegisterBackendImplementor(backend)
+
+	for _, c := range []struct {
+		name string
+		race func(key string) discovery.Backend
+	}{
+		{"read back", func(key string) discovery.Backend { return &racingBackend{Backend: backend, key: key} }},
+		{"create", func(key string) discovery.Backend { return &claimingBackend{Backend: backend, key: key} }},
+	} {
+		// The first slot tried is the hint of the prefix counter.
+		prefix := fmt.Sprintf("%s/collision_%s", PartitionPrefix, c.name)
+		hint, err := backend.Incr(prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raced := fmt.Sprintf("%s/%d", prefix, (hint+1)&testLayout.PartitionMask())
+		discovery.RegisterBackendImplementor(c.race(raced))
+
+		l := newTestLease("collision_"+c.name, "a")
+		if err = l.acquire(); err != nil {
+			t.Fatal(c.name, err)
+		}
+		if key := l.key.Load().(string); key == raced {
+			t.Fatal(c.name, "collided slot acquired", key)
+		}
+		if !l.Valid() {
+			t.Fatal(c.name, "lease not valid")
+		}
+		if pairs, _ := backend.Get(raced, false); len(pairs.KVs) != 1 || pairs.KVs[0].Value != "racer" {
+			t.Fatal(c.name, "collided slot overwritten")
+		}
+	}
+}
+
+func TestLease_Lost(t *testing.T) {
+	backend := discovery.BackendImplementor()
+	l := newTestLease("lost", "a")
+	if err := l.acquire(); err != nil {
+		t.Fatal(err)
+	}
+	// Taken over by another node, a new slot is acquired.
+	lost := l.key.Load().(string)
+	if err := backend.Set(lost, "b", 0); err != nil {
+		t.Fatal(err)
+	}
+	l.verify()
+	if key := l.key.Load().(string); key == lost || !l.Valid() {
+		t.Fatal("lease not moved to a free slot", key)
+	}
+	if _, err := l.flake.Next(); err != nil {
+		t.Fatal(err)
+	}
+	// Taken over again without free slots, stops generating IDs.
+	if err := backend.Set(l.key.Load().(string), "c", 0); err != nil {
+		t.Fatal(err)
+	}
+	l.verify()
+	if l.Valid() {
+		t.Fatal("lost lease valid")
+	}
+	if _, err := l.flake.Next(); err != sf.ErrPartitionLost {
+		t.Fatal("expected partition lost, got", err)
+	}
+	// Releasing a lost lease keeps the slot of the new owner.
+	if err := l.release(); err != nil {
+		t.Fatal(err)
+	}
+	if pairs, _ := backend.Get(lost, false); len(pairs.KVs) != 1 || pairs.KVs[0].Value != "b" {
+		t.Fatal("slot of the new owner released")
+	}
+}
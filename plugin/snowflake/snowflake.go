@@ -0,0 +1,112 @@
+package snowflake
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/appootb/substratum/v2/discovery"
+	ictx "github.com/appootb/substratum/v2/internal/context"
+	"github.com/appootb/substratum/v2/snowflake"
+	"github.com/appootb/substratum/v2/util/iphelper"
+	"github.com/appootb/substratum/v2/util/random"
+	sf "github.com/appootb/substratum/v2/util/snowflake"
+)
+
+func Init() {
+	if snowflake.Implementor() == nil {
+		snowflake.RegisterImplementor(NewGenerator())
+	}
+}
+
+const (
+	PartitionPrefix = "snowflake"
+	NodeComponent   = "_node_"
+)
+
+// NewGenerator returns a generator allocating partitions through the discovery backend,
+// the options, e.g. layout and epoch, apply to both the node-level and per-component snowflakes.
+func NewGenerator(opts ...sf.Option) *Generator {
+	if len(opts) > 0 {
+		sf.Default = sf.New(opts...)
+	}
+	ctx, cancel := context.WithCancel(ictx.Context)
+	return &Generator{
+		ctx:    ctx,
+		cancel: cancel,
+		owner:  fmt.Sprintf("%s-%s", iphelper.LocalIP(), random.String(16)),
+		opts:   opts,
+		leases: make(map[string]*lease),
+	}
+}
+
+type Generator struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	owner  string
+	opts   []sf.Option
+
+	mu     sync.RWMutex
+	leases map[string]*lease
+}
+
+// Allocate a partition ID for the component and keep it alive with the ttl.
+// An empty component name allocates the node-level partition shared by the process.
+func (g *Generator) Allocate(component string, ttl time.Duration) (*sf.Snowflake, error) {
+	name := component
+	if name == "" {
+		name = NodeComponent
+	}
+	if ttl <= 0 {
+		ttl = discovery.EmptyOptions().TTL
+	}
+	l := &lease{
+		prefix: fmt.Sprintf("%s/%s", PartitionPrefix, name),
+		owner:  g.owner,
+		ttl:    ttl,
+		flake:  sf.Default,
+	}
+	if component != "" {
+		l.flake = sf.New(g.opts...)
+	}
+	if err := l.acquire(); err != nil {
+		return nil, err
+	}
+	l.flake.SetLease(l)
+	go l.keepAlive(g.ctx)
+	//
+	g.mu.Lock()
+	g.leases[component] = l
+	g.mu.Unlock()
+	return l.flake, nil
+}
+
+// Get returns the snowflake of the component,
+// the node-level snowflake is returned if the component is not allocated.
+func (g *Generator) Get(component string) *sf.Snowflake {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if l, ok := g.leases[component]; ok {
+		return l.flake
+	}
+	return sf.Default
+}
+
+// Release all the allocated partition IDs.
+func (g *Generator) Release() error {
+	g.cancel()
+	//
+	g.mu.Lock()
+	leases := g.leases
+	g.leases = make(map[string]*lease)
+	g.mu.Unlock()
+	//
+	var err error
+	for _, l := range leases {
+		if e := l.release(); e != nil {
+			err = e
+		}
+	}
+	return err
+}
@@ -0,0 +1,97 @@
+package snowflake
+
+import (
+	"testing"
+	"time"
+
+	"github.com/appootb/substratum/v2/discovery"
+	sf "github.com/appootb/substratum/v2/util/snowflake"
+)
+
+func TestGenerator_Allocate(t *testing.T) {
+	g := NewGenerator()
+	defer g.Release()
+
+	keys := map[string]string{}
+	for _, c := range []struct {
+		component string
+		prefix    string
+	}{
+		{"", PartitionPrefix + "/" + NodeComponent},
+		{"allocate_a", PartitionPrefix + "/allocate_a"},
+		{"allocate_b", PartitionPrefix + "/allocate_b"},
+	} {
+		flake, err := g.Allocate(c.component, time.Minute)
+		if err != nil {
+			t.Fatal(c.component, err)
+		}
+		if (c.component == "") != (flake == sf.Default) {
+			t.Fatal(c.component, "unexpected node-level snowflake")
+		}
+		if g.Get(c.component) != flake {
+			t.Fatal(c.component, "allocated snowflake not returned")
+		}
+		l := g.leases[c.component]
+		if l.prefix != c.prefix || !l.Valid() {
+			t.Fatal(c.component, "unexpected lease", l.prefix)
+		}
+		if id, err := flake.Next(); err != nil || flake.PartitionID(id) != uint64(l.partition) {
+			t.Fatal(c.component, "unexpected partition of id", id, err)
+		}
+		keys[c.component] = l.key.Load().(string)
+	}
+	// Not allocated, the node-level snowflake is shared.
+	if g.Get("allocate_c") != sf.Default {
+		t.Fatal("node-level snowflake not shared")
+	}
+	// Each component has its own generator.
+	if g.Get("allocate_a") == g.Get("allocate_b") {
+		t.Fatal("snowflake shared by components")
+	}
+
+	if err := g.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if g.Get("allocate_a") != sf.Default {
+		t.Fatal("released snowflake returned")
+	}
+	// Released slots are deleted.
+	for component, key := range keys {
+		if pairs, err := discovery.BackendImplementor().Get(key, false); err != nil || len(pairs.KVs) != 0 {
+			t.Fatal(component, "slot not released", key, err)
+		}
+	}
+}
+
+func TestGenerator_Options(t *testing.T) {
+	defer func(flake *sf.Snowflake) {
+		sf.Default = flake
+	}(sf.Default)
+
+	epoch := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	g := NewGenerator(sf.WithLayout(testLayout), sf.WithEpoch(epoch))
+	defer g.Release()
+
+	for _, component := range []string{"", "options"} {
+		flake, err := g.Allocate(component, time.Minute)
+		if err != nil {
+			t.Fatal(component, err)
+		}
+		if flake.Layout() != testLayout {
+			t.Fatal(component, "layout not configured", flake.Layout())
+		}
+		id, err := flake.Next()
+		if err != nil {
+			t.Fatal(component, err)
+		}
+		ts, partition, _ := flake.Decode(id)
+		if time.Since(ts) > time.Minute || partition != g.leases[component].partition {
+			t.Fatal(component, "unexpected id", ts, partition)
+		}
+		// Elapsed milliseconds since the configured epoch.
+		elapsed := time.Duration(id>>(testLayout.PartitionBits+testLayout.SequenceBits)) * time.Millisecond
+		if d := time.Since(epoch) - elapsed; d < 0 || d > time.Minute {
+			t.Fatal(component, "epoch not configured", elapsed)
+		}
+	}
+}
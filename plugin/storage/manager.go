@@ -25,6 +25,17 @@ func (m *Manager) New(component string) {
 	})
 }
 
+func (m *Manager) Close() error {
+	var err error
+	m.Range(func(_, s interface{}) bool {
+		if e := s.(*Storage).Close(); e != nil {
+			err = e
+		}
+		return true
+	})
+	return err
+}
+
 func (m *Manager) Get(component string) storage.Storage {
 	s, ok := m.Load(component)
 	if ok {
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"io"
 	"sync"
 	"sync/atomic"
 
@@ -132,3 +133,35 @@ func (s *Storage) GetCommon(schema configure.Schema) interface{} {
 	s.mu.RUnlock()
 	return s.common[schema]
 }
+
+func (s *Storage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var err error
+	for _, db := range append([]*gorm.DB{s.masterDB}, s.slaveDBs...) {
+		if db == nil {
+			continue
+		}
+		if sqlDB, e := db.DB(); e == nil {
+			if e = sqlDB.Close(); e != nil {
+				err = e
+			}
+		}
+	}
+	for _, cache := range s.caches {
+		if c, ok := cache.(io.Closer); ok {
+			if e := c.Close(); e != nil {
+				err = e
+			}
+		}
+	}
+	for _, dialect := range s.common {
+		if c, ok := dialect.(io.Closer); ok {
+			if e := c.Close(); e != nil {
+				err = e
+			}
+		}
+	}
+	return err
+}
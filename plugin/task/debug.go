@@ -11,7 +11,7 @@ func Init() {
 		task.RegisterLockerImplementor(&Debug{})
 	}
 	if task.Implementor() == nil {
-		task.RegisterImplementor(&Task{})
+		task.RegisterImplementor(newTask())
 	}
 }
 
@@ -1,9 +1,11 @@
 package task
 
 import (
+	"context"
 	"crypto/sha1"
 	"fmt"
 	"reflect"
+	"sync"
 	"time"
 
 	sctx "github.com/appootb/substratum/v2/context"
@@ -23,7 +25,19 @@ const (
 	LogError    = logger.LogTag + "error"
 )
 
-type Task struct{}
+func newTask() task.Task {
+	ctx, cancel := context.WithCancel(ictx.Context)
+	return &Task{
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+type Task struct {
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
 
 func (c *Task) Schedule(spec string, exec task.Executor, opts ...task.Option) error {
 	options := task.EmptyOptions()
@@ -37,10 +51,27 @@ func (c *Task) Schedule(spec string, exec task.Executor, opts ...task.Option) er
 	if err != nil {
 		return err
 	}
+	c.wg.Add(1)
 	go c.exec(schedule, exec, options)
 	return nil
 }
 
+// Shutdown stops scheduling, waits for the running executions and releases the singleton lockers.
+func (c *Task) Shutdown(ctx context.Context) error {
+	c.cancel()
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (c *Task) reflectName(exec task.Executor) string {
 	t := reflect.TypeOf(exec)
 	// reflect.Ptr's PkgPath and Name is empty
@@ -51,8 +82,10 @@ func (c *Task) reflectName(exec task.Executor) string {
 }
 
 func (c *Task) exec(schedule scheduler.Schedule, exec task.Executor, opts *task.Options) {
+	defer c.wg.Done()
+
 Reset:
-	ctx := ictx.Context
+	ctx := c.ctx
 	if opts.Singleton {
 		// Blocked before acquired the locker.
 		ctx = task.LockerImplementor().Lock(ctx, opts.Name)
@@ -65,7 +98,7 @@ Reset:
 		select {
 		case <-ctx.Done():
 			select {
-			case <-ictx.Context.Done():
+			case <-c.ctx.Done():
 				if opts.Singleton {
 					task.LockerImplementor().Unlock(opts.Name)
 				}
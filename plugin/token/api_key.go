@@ -0,0 +1,159 @@
+package token
+
+import (
+	"context"
+	"crypto/subtle"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/appootb/substratum/v2/credential"
+	"github.com/appootb/substratum/v2/proto/go/common"
+	"github.com/appootb/substratum/v2/proto/go/secret"
+	"github.com/appootb/substratum/v2/storage"
+	"github.com/appootb/substratum/v2/token"
+	"github.com/appootb/substratum/v2/util/cache"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	DefaultReplayWindow = time.Minute * 5
+	// Size of the cache of the seen signatures within the replay window.
+	DefaultSignatureCacheSize = 65536
+	// RedisNoncePrefix is the key prefix of the seen signatures in redis.
+	RedisNoncePrefix = "substratum:nonce"
+)
+
+// NonceStore records the seen signatures within the replay window.
+type NonceStore interface {
+	// Add the nonce with the ttl, returns false if it is already added.
+	Add(nonce string, ttl time.Duration) (bool, error)
+}
+
+type APIKeyOption func(*APIKeyParser)
+
+// WithNonceStore sets the store of the seen signatures, defaults to an in-memory store of the process.
+// A shared store, e.g. RedisNonceStore, is required to reject the requests replayed against other nodes.
+func WithNonceStore(store NonceStore) APIKeyOption {
+	return func(p *APIKeyParser) {
+		p.nonces = store
+	}
+}
+
+// APIKeyParser verifies the requests signed by API keys,
+// the request timestamp must be within the replay window and the signature can only be used once.
+//
+// The body digest is computed by the gateway for HTTP requests. For native gRPC calls,
+// the digest is sent by the client as metadata and not bound to the request message.
+type APIKeyParser struct {
+	window time.Duration
+	nonces NonceStore
+}
+
+func NewAPIKeyParser(window time.Duration, opts ...APIKeyOption) *APIKeyParser {
+	if window <= 0 {
+		window = DefaultReplayWindow
+	}
+	p := &APIKeyParser{
+		window: window,
+		nonces: newLocalNonceStore(DefaultSignatureCacheSize),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// localNonceStore is the in-memory NonceStore of the process.
+type localNonceStore struct {
+	mu   sync.Mutex
+	seen cache.Cache
+}
+
+func newLocalNonceStore(size int) *localNonceStore {
+	return &localNonceStore{
+		seen: cache.New(cache.LRU, cache.WithSize(size)),
+	}
+}
+
+func (s *localNonceStore) Add(nonce string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen.Contain(nonce) {
+		return false, nil
+	}
+	s.seen.Set(nonce, true, ttl)
+	return true, nil
+}
+
+// RedisNonceStore is the NonceStore shared across nodes, built on the redis clients of the storage.
+type RedisNonceStore struct {
+	storage storage.Storage
+}
+
+func NewRedisNonceStore(s storage.Storage) *RedisNonceStore {
+	return &RedisNonceStore{
+		storage: s,
+	}
+}
+
+func (s *RedisNonceStore) Add(nonce string, ttl time.Duration) (bool, error) {
+	key := RedisNoncePrefix + ":" + nonce
+	return s.storage.GetRedis(key).SetNX(context.Background(), key, 1, ttl).Result()
+}
+
+// Parse the API key signature of the request metadata.
+func (p *APIKeyParser) Parse(md *common.Metadata) (*secret.Info, error) {
+	if !strings.HasPrefix(md.GetToken(), token.APIKeyScheme) {
+		return nil, status.Error(codes.Unauthenticated, "substratum: invalid api key token")
+	}
+	parts := strings.SplitN(strings.TrimPrefix(md.GetToken(), token.APIKeyScheme), ":", 2)
+	if len(parts) != 2 {
+		return nil, status.Error(codes.Unauthenticated, "substratum: invalid api key token")
+	}
+	keyID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "substratum: invalid api key id")
+	}
+	// Replay window.
+	signedAt := time.Unix(0, md.GetTimestamp()*int64(time.Millisecond))
+	if d := time.Since(signedAt); d > p.window || d < -p.window {
+		return nil, status.Error(codes.Unauthenticated, "substratum: request timestamp out of replay window")
+	}
+	if md.GetBodyDigest() == "" {
+		return nil, status.Error(codes.Unauthenticated, "substratum: request body digest required")
+	}
+	key, err := credential.APIKeyImplementor().Get(keyID)
+	if err != nil {
+		return nil, err
+	}
+	signature := token.APIKeySignature(keyID, key.Secret, md.GetTimestamp(), md.GetBodyDigest())
+	if subtle.ConstantTimeCompare([]byte(signature), []byte(parts[1])) != 1 {
+		return nil, status.Error(codes.Unauthenticated, "substratum: invalid api key signature")
+	}
+	added, err := p.nonces.Add(signature, p.window*2)
+	if err != nil {
+		return nil, err
+	}
+	if !added {
+		return nil, status.Error(codes.Unauthenticated, "substratum: api key signature replayed")
+	}
+
+	roles := key.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+	return &secret.Info{
+		Type:      secret.Type_API_KEY,
+		Algorithm: secret.Algorithm_HMAC,
+		Account:   key.Account,
+		KeyId:     key.ID,
+		Roles:     roles,
+		Subject:   key.Subject,
+		IssuedAt:  timestamppb.New(signedAt),
+		ExpiredAt: timestamppb.New(signedAt.Add(p.window)),
+	}, nil
+}
@@ -0,0 +1,90 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	sctx "github.com/appootb/substratum/v2/credential"
+	"github.com/appootb/substratum/v2/proto/go/common"
+	"github.com/appootb/substratum/v2/proto/go/permission"
+	"github.com/appootb/substratum/v2/proto/go/secret"
+	"github.com/appootb/substratum/v2/token"
+	"github.com/appootb/substratum/v2/util/hash"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAPIKeyParser_Parse(t *testing.T) {
+	key := &sctx.APIKey{
+		ID:      hash.Sum("TestAPIKeyParser_Parse"),
+		Account: 123456789,
+		Secret:  []byte("TestAPIKeyParser_Parse"),
+		Subject: permission.Subject_SERVER,
+		Roles:   []string{"partner"},
+	}
+	if err := sctx.APIKeyImplementor().Add(key); err != nil {
+		t.Fatal(err)
+	}
+	p := NewAPIKeyParser(time.Minute)
+	sign := func(timestamp int64, body string) *common.Metadata {
+		digest := token.BodyDigest([]byte(body))
+		return &common.Metadata{
+			Timestamp:  timestamp,
+			BodyDigest: digest,
+			Token:      token.SignAPIKey(key.ID, key.Secret, timestamp, digest),
+		}
+	}
+
+	now := time.Now().UnixNano() / 1e6
+	md := sign(now, `{"id":1}`)
+	s, err := p.Parse(md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.GetType() != secret.Type_API_KEY || s.GetAccount() != key.Account || s.GetRoles()[0] != "partner" {
+		t.Fatal("bad secret info", s)
+	}
+	// Replayed.
+	if _, err = p.Parse(md); status.Code(err) != codes.Unauthenticated {
+		t.Fatal(err)
+	}
+	// Out of the replay window.
+	if _, err = p.Parse(sign(now-time.Hour.Milliseconds(), `{"id":1}`)); status.Code(err) != codes.Unauthenticated {
+		t.Fatal(err)
+	}
+	// Body tampered.
+	md = sign(now+1, `{"id":1}`)
+	md.BodyDigest = token.BodyDigest([]byte(`{"id":2}`))
+	if _, err = p.Parse(md); status.Code(err) != codes.Unauthenticated {
+		t.Fatal(err)
+	}
+}
+
+func TestAPIKeyParser_SharedNonceStore(t *testing.T) {
+	key := &sctx.APIKey{
+		ID:      hash.Sum("TestAPIKeyParser_SharedNonceStore"),
+		Account: 123456789,
+		Secret:  []byte("TestAPIKeyParser_SharedNonceStore"),
+		Subject: permission.Subject_SERVER,
+	}
+	if err := sctx.APIKeyImplementor().Add(key); err != nil {
+		t.Fatal(err)
+	}
+	store := newLocalNonceStore(16)
+	node1 := NewAPIKeyParser(time.Minute, WithNonceStore(store))
+	node2 := NewAPIKeyParser(time.Minute, WithNonceStore(store))
+
+	now := time.Now().UnixNano() / 1e6
+	digest := token.BodyDigest(nil)
+	md := &common.Metadata{
+		Timestamp:  now,
+		BodyDigest: digest,
+		Token:      token.SignAPIKey(key.ID, key.Secret, now, digest),
+	}
+	if _, err := node1.Parse(md); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := node2.Parse(md); status.Code(err) != codes.Unauthenticated {
+		t.Fatal("replayed against another node", err)
+	}
+}
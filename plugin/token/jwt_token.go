@@ -7,6 +7,7 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
@@ -18,8 +19,11 @@ import (
 	"github.com/appootb/substratum/v2/proto/go/permission"
 	"github.com/appootb/substratum/v2/proto/go/secret"
 	"github.com/appootb/substratum/v2/token"
+	"github.com/appootb/substratum/v2/util/jwk"
 	"github.com/gbrlsnchs/jwt/v3"
 	"github.com/gbrlsnchs/jwt/v3/jwtutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -59,6 +63,29 @@ func (t *JwtToken) sign(s *secret.Info, key []byte) (string, error) {
 	return string(val), nil
 }
 
+// signServer signs the server token with the algorithm of the server key.
+func (t *JwtToken) signServer(s *secret.Info, key *credential.ServerKey) (string, error) {
+	s.KeyId = key.ID
+	s.Algorithm = key.KeyAlgorithm()
+	return t.sign(s, key.Key)
+}
+
+// clientKeyAlgorithms returns the algorithms of the client key created by NewSecretKey.
+func clientKeyAlgorithms(key []byte) []secret.Algorithm {
+	if _, err := x509.ParsePKCS1PrivateKey(key); err == nil {
+		return []secret.Algorithm{secret.Algorithm_RSA, secret.Algorithm_PSS}
+	}
+	if _, err := x509.ParseECPrivateKey(key); err == nil {
+		return []secret.Algorithm{secret.Algorithm_ECDSA}
+	}
+	if len(key) == ed25519.PrivateKeySize {
+		return []secret.Algorithm{secret.Algorithm_EdDSA}
+	}
+	return []secret.Algorithm{secret.Algorithm_HMAC}
+}
+
+// getAlgorithm returns the algorithm of the private key for signing and verification,
+// or the PKIX public key for verification only.
 func (t *JwtToken) getAlgorithm(alg secret.Algorithm, key []byte) (jwt.Algorithm, error) {
 	switch alg {
 	case secret.Algorithm_HMAC:
@@ -66,29 +93,85 @@ func (t *JwtToken) getAlgorithm(alg secret.Algorithm, key []byte) (jwt.Algorithm
 	case secret.Algorithm_RSA:
 		priv, err := x509.ParsePKCS1PrivateKey(key)
 		if err != nil {
-			return nil, err
+			pub, ok := t.parsePublicKey(key).(*rsa.PublicKey)
+			if !ok {
+				return nil, err
+			}
+			return jwt.NewRS512(jwt.RSAPublicKey(pub)), nil
 		}
 		return jwt.NewRS512(jwt.RSAPrivateKey(priv), jwt.RSAPublicKey(&priv.PublicKey)), nil
 	case secret.Algorithm_PSS:
 		priv, err := x509.ParsePKCS1PrivateKey(key)
 		if err != nil {
-			return nil, err
+			pub, ok := t.parsePublicKey(key).(*rsa.PublicKey)
+			if !ok {
+				return nil, err
+			}
+			return jwt.NewPS512(jwt.RSAPublicKey(pub)), nil
 		}
 		return jwt.NewPS512(jwt.RSAPrivateKey(priv), jwt.RSAPublicKey(&priv.PublicKey)), nil
 	case secret.Algorithm_ECDSA:
 		priv, err := x509.ParseECPrivateKey(key)
 		if err != nil {
-			return nil, err
+			pub, ok := t.parsePublicKey(key).(*ecdsa.PublicKey)
+			if !ok {
+				return nil, err
+			}
+			return t.newECDSA(pub.Curve, jwt.ECDSAPublicKey(pub))
 		}
-		return jwt.NewES512(jwt.ECDSAPrivateKey(priv), jwt.ECDSAPublicKey(&priv.PublicKey)), nil
+		return t.newECDSA(priv.Curve, jwt.ECDSAPrivateKey(priv), jwt.ECDSAPublicKey(&priv.PublicKey))
 	case secret.Algorithm_EdDSA:
+		if pub, ok := t.parsePublicKey(key).(ed25519.PublicKey); ok {
+			return jwt.NewEd25519(jwt.Ed25519PublicKey(pub)), nil
+		}
+		if len(key) != ed25519.PrivateKeySize {
+			return nil, UnsupportedAlgorithm
+		}
 		priv := ed25519.PrivateKey(key)
-		return jwt.NewEd25519(jwt.Ed25519PrivateKey(priv), jwt.Ed25519PublicKey(priv.Public().([]byte))), nil
+		return jwt.NewEd25519(jwt.Ed25519PrivateKey(priv), jwt.Ed25519PublicKey(priv.Public().(ed25519.PublicKey))), nil
+	default:
+		return nil, UnsupportedAlgorithm
+	}
+}
+
+// newECDSA returns the ECDSA algorithm of the key curve.
+func (t *JwtToken) newECDSA(curve elliptic.Curve, opts ...func(*jwt.ECDSASHA)) (jwt.Algorithm, error) {
+	switch token.ECDSAAlgorithmNames[curve.Params().Name] {
+	case "ES256":
+		return jwt.NewES256(opts...), nil
+	case "ES384":
+		return jwt.NewES384(opts...), nil
+	case "ES512":
+		return jwt.NewES512(opts...), nil
 	default:
 		return nil, UnsupportedAlgorithm
 	}
 }
 
+// checkRevoked returns an error if the client session is revoked.
+func (t *JwtToken) checkRevoked(accountID uint64, keyID int64) error {
+	store := credential.SessionImplementor()
+	if store == nil {
+		return nil
+	}
+	revoked, err := store.IsRevoked(accountID, keyID)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return status.Error(codes.Unauthenticated, "substratum: token revoked")
+	}
+	return nil
+}
+
+func (t *JwtToken) parsePublicKey(key []byte) interface{} {
+	pub, err := x509.ParsePKIXPublicKey(key)
+	if err != nil {
+		return nil
+	}
+	return pub
+}
+
 func (t *JwtToken) NewSecretKey(alg secret.Algorithm) ([]byte, error) {
 	switch alg {
 	case secret.Algorithm_HMAC:
@@ -116,14 +199,32 @@ func (t *JwtToken) NewSecretKey(alg secret.Algorithm) ([]byte, error) {
 }
 
 // Generate a new token with specified options.
+// The platform and device of the client session are not recorded, use GenerateWithMetadata instead.
 func (t *JwtToken) Generate(s *secret.Info) (string, error) {
+	return t.GenerateWithMetadata(s, nil)
+}
+
+// GenerateWithMetadata generates a new token and records the client session with the request metadata.
+func (t *JwtToken) GenerateWithMetadata(s *secret.Info, md *common.Metadata) (string, error) {
+	return t.generate(s, md, 0)
+}
+
+// generate a new token, the client session is recorded with the key ID of the paired refresh token.
+func (t *JwtToken) generate(s *secret.Info, md *common.Metadata, refreshID int64) (string, error) {
 	if s.GetType() == secret.Type_SERVER {
-		// TODO: Server key should be added by operators.
-		key, err := credential.ServerImplementor().Get(s.GetKeyId())
+		var (
+			err error
+			key *credential.ServerKey
+		)
+		if s.GetKeyId() == credential.CurrentKeyID {
+			key, err = credential.CurrentServerKey(credential.ServerImplementor())
+		} else {
+			key, err = credential.GetServerKey(credential.ServerImplementor(), s.GetKeyId())
+		}
 		if err != nil {
 			return "", err
 		}
-		return t.sign(s, key)
+		return t.signServer(s, key)
 	}
 
 	key, err := t.NewSecretKey(s.GetAlgorithm())
@@ -134,17 +235,31 @@ func (t *JwtToken) Generate(s *secret.Info) (string, error) {
 	if err = credential.ClientImplementor().Add(s.GetAccount(), s.GetKeyId(), key, dur); err != nil {
 		return "", err
 	}
+	if store := credential.SessionImplementor(); store != nil {
+		err = store.AddSession(s.GetAccount(), &credential.Session{
+			KeyID:     s.GetKeyId(),
+			Platform:  md.GetPlatform(),
+			DeviceID:  md.GetDeviceId(),
+			IssuedAt:  s.GetIssuedAt().AsTime(),
+			ExpiredAt: s.GetExpiredAt().AsTime(),
+			RefreshID: refreshID,
+		})
+		if err != nil {
+			return "", err
+		}
+	}
 	return t.sign(s, key)
 }
 
 // Refresh the token with expired time renewed.
 func (t *JwtToken) Refresh(s *secret.Info) (string, error) {
 	var (
-		err error
-		key []byte
+		err       error
+		key       []byte
+		serverKey *credential.ServerKey
 	)
 	if s.GetType() == secret.Type_SERVER {
-		key, err = credential.ServerImplementor().Get(s.GetKeyId())
+		serverKey, err = credential.GetServerKey(credential.ServerImplementor(), s.GetKeyId())
 	} else {
 		dur := s.GetExpiredAt().AsTime().Sub(s.GetIssuedAt().AsTime())
 		key, err = credential.ClientImplementor().Refresh(s.GetAccount(), s.GetKeyId(), dur)
@@ -158,6 +273,14 @@ func (t *JwtToken) Refresh(s *secret.Info) (string, error) {
 	now := time.Now()
 	s.IssuedAt = timestamppb.New(now)
 	s.ExpiredAt = timestamppb.New(now.Add(expiredAt.Sub(issuedAt)))
+	if store := credential.SessionImplementor(); store != nil && s.GetType() != secret.Type_SERVER {
+		if err = store.RefreshSession(s.GetAccount(), s.GetKeyId(), s.GetExpiredAt().AsTime()); err != nil {
+			return "", err
+		}
+	}
+	if serverKey != nil {
+		return t.signServer(s, serverKey)
+	}
 	return t.sign(s, key)
 }
 
@@ -167,7 +290,7 @@ func (t *JwtToken) Parse(md *common.Metadata) (*secret.Info, error) {
 }
 
 // ParseRaw parses a token string.
-func (t *JwtToken) ParseRaw(token string) (*secret.Info, error) {
+func (t *JwtToken) ParseRaw(val string) (*secret.Info, error) {
 	var (
 		accountID uint64
 		keyID     int64
@@ -176,38 +299,37 @@ func (t *JwtToken) ParseRaw(token string) (*secret.Info, error) {
 	resolver := &jwtutil.Resolver{
 		New: func(header jwt.Header) (jwt.Algorithm, error) {
 			var (
-				err error
-				key []byte
+				err     error
+				key     []byte
+				allowed []secret.Algorithm
 			)
 			keyIDs := strings.Split(header.KeyID, "-")
-			if len(keyIDs) != 2 {
+			if len(keyIDs) != 2 || header.ContentType == secret.Type_API_KEY.String() {
 				return nil, jwt.ErrAlgValidation
 			}
 			accountID, _ = strconv.ParseUint(keyIDs[0], 10, 64)
 			keyID, _ = strconv.ParseInt(keyIDs[1], 10, 64)
 			if header.ContentType == secret.Type_SERVER.String() {
-				key, err = credential.ServerImplementor().Get(keyID)
-			} else {
-				key, err = credential.ClientImplementor().Get(accountID, keyID)
+				var serverKey *credential.ServerKey
+				if serverKey, err = credential.GetServerKey(credential.ServerImplementor(), keyID); err == nil {
+					key, allowed = serverKey.Key, []secret.Algorithm{serverKey.KeyAlgorithm()}
+				}
+			} else if err = t.checkRevoked(accountID, keyID); err == nil {
+				if key, err = credential.ClientImplementor().Get(accountID, keyID); err == nil {
+					allowed = clientKeyAlgorithms(key)
+				}
 			}
 			if err != nil {
 				return nil, err
 			}
-			switch header.Algorithm {
-			case "HS512":
-				alg = secret.Algorithm_HMAC
-			case "RS512":
-				alg = secret.Algorithm_RSA
-			case "PS512":
-				alg = secret.Algorithm_PSS
-			case "ES512":
-				alg = secret.Algorithm_ECDSA
-			case "Ed25519":
-				alg = secret.Algorithm_EdDSA
-			default:
-				alg = secret.Algorithm_None
+			// The algorithm of the header must match the key, e.g. HMAC is never verified with a public key.
+			alg = token.AlgorithmByName(header.Algorithm)
+			for _, a := range allowed {
+				if a == alg {
+					return t.getAlgorithm(alg, key)
+				}
 			}
-			return t.getAlgorithm(alg, key)
+			return nil, jwt.ErrAlgValidation
 		},
 	}
 	// Verify
@@ -216,7 +338,7 @@ func (t *JwtToken) ParseRaw(token string) (*secret.Info, error) {
 	validator := jwt.ValidatePayload(&payload,
 		jwt.NotBeforeValidator(now),
 		jwt.ExpirationTimeValidator(now))
-	header, err := jwt.Verify([]byte(token), resolver, &payload, validator)
+	header, err := jwt.Verify([]byte(val), resolver, &payload, validator)
 	if err != nil {
 		return nil, err
 	}
@@ -238,3 +360,36 @@ func (t *JwtToken) ParseRaw(token string) (*secret.Info, error) {
 		ExpiredAt: timestamppb.New(payload.ExpirationTime.Time),
 	}, nil
 }
+
+// JWKS returns the JSON Web Key Set of the asymmetric server keys, symmetric keys are skipped.
+// The key set is empty if the server seed is not a credential.ServerKeyStore.
+func (t *JwtToken) JWKS() ([]byte, error) {
+	var keys []*credential.ServerKey
+	if store, ok := credential.ServerImplementor().(credential.ServerKeyStore); ok {
+		var err error
+		if keys, err = store.Keys(); err != nil {
+			return nil, err
+		}
+	}
+	set := jwk.Set{
+		Keys: make([]*jwk.Key, 0, len(keys)),
+	}
+	for _, key := range keys {
+		pub, err := key.PublicKey()
+		if err == jwk.ErrSymmetricKey {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		k, err := jwk.New(strconv.FormatInt(key.ID, 10), pub)
+		if err != nil {
+			return nil, err
+		}
+		k.Alg = token.AlgorithmNames[key.KeyAlgorithm()]
+		if k.Kty == "EC" {
+			k.Alg = token.ECDSAAlgorithmNames[k.Crv]
+		}
+		set.Keys = append(set.Keys, k)
+	}
+	return json.Marshal(&set)
+}
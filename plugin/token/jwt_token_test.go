@@ -1,19 +1,40 @@
 package token
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	sctx "github.com/appootb/substratum/v2/credential"
+	"github.com/appootb/substratum/v2/metadata"
 	"github.com/appootb/substratum/v2/plugin/credential"
+	"github.com/appootb/substratum/v2/plugin/logger"
+	"github.com/appootb/substratum/v2/proto/go/common"
 	"github.com/appootb/substratum/v2/proto/go/permission"
 	"github.com/appootb/substratum/v2/proto/go/secret"
+	"github.com/appootb/substratum/v2/token"
 	"github.com/appootb/substratum/v2/util/hash"
+	"github.com/appootb/substratum/v2/util/jwk"
 	"github.com/gbrlsnchs/jwt/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func init() {
 	credential.Init()
+	logger.Init()
 }
 
 func TestJwtToken_Generate(t *testing.T) {
@@ -43,12 +64,16 @@ func TestJwtToken_Generate(t *testing.T) {
 func TestJwtToken_Expire(t *testing.T) {
 	j := &JwtToken{}
 	now := time.Now()
+	keyID := hash.Sum("TestJwtToken_Expire")
+	if err := sctx.ServerImplementor().Add(keyID, []byte("TestJwtToken_Expire")); err != nil {
+		t.Fatal(err)
+	}
 	token, err := j.Generate(&secret.Info{
 		Type:      secret.Type_SERVER,
 		Algorithm: secret.Algorithm_HMAC,
 		Issuer:    "appootb",
 		Account:   123456789,
-		KeyId:     hash.Sum("TestJwtToken_Expire"),
+		KeyId:     keyID,
 		Subject:   permission.Subject_SERVER,
 		IssuedAt:  timestamppb.New(now.Add(-time.Hour)),
 		ExpiredAt: timestamppb.New(now.Add(-time.Minute)),
@@ -62,6 +87,27 @@ func TestJwtToken_Expire(t *testing.T) {
 	}
 }
 
+func TestJwtToken_UnknownServerKey(t *testing.T) {
+	if metadata.EnvDevelop != "" {
+		t.Skip("default server key is allowed in develop mode")
+	}
+	j := &JwtToken{}
+	now := time.Now()
+	_, err := j.Generate(&secret.Info{
+		Type:      secret.Type_SERVER,
+		Algorithm: secret.Algorithm_HMAC,
+		Issuer:    "appootb",
+		Account:   123456789,
+		KeyId:     hash.Sum("TestJwtToken_UnknownServerKey"),
+		Subject:   permission.Subject_SERVER,
+		IssuedAt:  timestamppb.New(now),
+		ExpiredAt: timestamppb.New(now.Add(time.Hour)),
+	})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatal(err)
+	}
+}
+
 func TestJwtToken_Before(t *testing.T) {
 	j := &JwtToken{}
 	now := time.Now()
@@ -104,3 +150,493 @@ func TestJwtToken_TokenNotBefore(t *testing.T) {
 		t.Fatal("fail: invalid token")
 	}
 }
+
+func TestJwtToken_JWKS(t *testing.T) {
+	j := &JwtToken{}
+	keyID := hash.Sum("TestJwtToken_JWKS")
+	key, err := j.NewSecretKey(secret.Algorithm_RSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = sctx.ServerImplementor().Add(keyID, key); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	token, err := j.Generate(&secret.Info{
+		Type:      secret.Type_SERVER,
+		Algorithm: secret.Algorithm_RSA,
+		Issuer:    "appootb",
+		Account:   123456789,
+		KeyId:     keyID,
+		Subject:   permission.Subject_SERVER,
+		IssuedAt:  timestamppb.New(now),
+		ExpiredAt: timestamppb.New(now.Add(time.Hour)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks, err := j.JWKS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(jwks)
+	}))
+	defer srv.Close()
+
+	signer := sctx.ServerImplementor()
+	defer sctx.RegisterServerImplementor(signer)
+	sctx.RegisterServerImplementor(credential.NewJWKSSeed(srv.URL, time.Minute))
+
+	s, err := j.ParseRaw(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.GetKeyId() != keyID || s.GetAlgorithm() != secret.Algorithm_RSA {
+		t.Fatal("bad secret info")
+	}
+	if _, err = j.Refresh(s); err == nil {
+		t.Fatal("signed with public-only key")
+	}
+}
+
+func TestJwtToken_ECDSACurves(t *testing.T) {
+	defer sctx.RegisterServerImplementor(sctx.ServerImplementor())
+
+	j := &JwtToken{}
+	for name, curve := range map[string]elliptic.Curve{
+		"ES256": elliptic.P256(),
+		"ES384": elliptic.P384(),
+		"ES512": elliptic.P521(),
+	} {
+		priv, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := x509.MarshalECPrivateKey(priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seed := &credential.ServerSeed{}
+		sctx.RegisterServerImplementor(seed)
+		if err = seed.AddKey(&sctx.ServerKey{ID: 1, Key: key}); err != nil {
+			t.Fatal(err)
+		}
+		now := time.Now()
+		val, err := j.Generate(&secret.Info{
+			Type:      secret.Type_SERVER,
+			Issuer:    "appootb",
+			KeyId:     1,
+			Subject:   permission.Subject_SERVER,
+			IssuedAt:  timestamppb.New(now),
+			ExpiredAt: timestamppb.New(now.Add(time.Hour)),
+		})
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		var header jwt.Header
+		buf, _ := base64.RawURLEncoding.DecodeString(strings.SplitN(val, ".", 2)[0])
+		if err = json.Unmarshal(buf, &header); err != nil || header.Algorithm != name {
+			t.Fatal("signed with", header.Algorithm, "expected", name, err)
+		}
+
+		jwks, err := j.JWKS()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var set jwk.Set
+		if err = json.Unmarshal(jwks, &set); err != nil || len(set.Keys) != 1 || set.Keys[0].Alg != name {
+			t.Fatal("unexpected key set", string(jwks), err)
+		}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write(jwks)
+		}))
+		sctx.RegisterServerImplementor(credential.NewJWKSSeed(srv.URL, time.Minute))
+		_, err = j.ParseRaw(val)
+		srv.Close()
+		if err != nil {
+			t.Fatal(name, err)
+		}
+	}
+}
+
+func TestJwtToken_ServerKeyRotation(t *testing.T) {
+	seed := &credential.ServerSeed{}
+	defer sctx.RegisterServerImplementor(sctx.ServerImplementor())
+	sctx.RegisterServerImplementor(seed)
+
+	j := &JwtToken{}
+	generate := func() (*secret.Info, string) {
+		now := time.Now()
+		info := &secret.Info{
+			Type:      secret.Type_SERVER,
+			Algorithm: secret.Algorithm_HMAC,
+			Issuer:    "appootb",
+			KeyId:     sctx.CurrentKeyID,
+			Subject:   permission.Subject_SERVER,
+			IssuedAt:  timestamppb.New(now),
+			ExpiredAt: timestamppb.New(now.Add(time.Hour)),
+		}
+		token, err := j.Generate(info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info, token
+	}
+
+	if err := seed.Add(1, []byte("TestJwtToken_ServerKeyRotation_1")); err != nil {
+		t.Fatal(err)
+	}
+	info, oldToken := generate()
+	if info.GetKeyId() != 1 {
+		t.Fatal("signed with key", info.GetKeyId())
+	}
+	// Rotate now, the old key is valid within the overlap.
+	now := time.Now()
+	err := sctx.Rotate(seed, &sctx.ServerKey{
+		ID:        2,
+		Key:       []byte("TestJwtToken_ServerKeyRotation_2"),
+		NotBefore: now,
+	}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, _ = generate(); info.GetKeyId() != 2 {
+		t.Fatal("signed with key", info.GetKeyId())
+	}
+	if _, err = j.ParseRaw(oldToken); err != nil {
+		t.Fatal(err)
+	}
+	// Scheduled key is not used before it starts.
+	err = sctx.Rotate(seed, &sctx.ServerKey{
+		ID:        3,
+		Key:       []byte("TestJwtToken_ServerKeyRotation_3"),
+		NotBefore: now.Add(time.Hour),
+	}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, _ = generate(); info.GetKeyId() != 2 {
+		t.Fatal("signed with key", info.GetKeyId())
+	}
+	if _, err = seed.Get(3); status.Code(err) != codes.Unauthenticated {
+		t.Fatal(err)
+	}
+	// Expired key is rejected.
+	err = seed.AddKey(&sctx.ServerKey{
+		ID:       1,
+		Key:      []byte("TestJwtToken_ServerKeyRotation_1"),
+		NotAfter: now,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = j.ParseRaw(oldToken); status.Code(err) != codes.Unauthenticated {
+		t.Fatal(err)
+	}
+}
+
+// legacyServerSeed implements the server seed without the key metadata.
+type legacyServerSeed map[int64][]byte
+
+func (s legacyServerSeed) Add(keyID int64, key []byte) error {
+	s[keyID] = key
+	return nil
+}
+
+func (s legacyServerSeed) Get(keyID int64) ([]byte, error) {
+	if key, ok := s[keyID]; ok {
+		return key, nil
+	}
+	return nil, status.Error(codes.Unauthenticated, "key not found")
+}
+
+func (s legacyServerSeed) Revoke(keyID int64) error {
+	delete(s, keyID)
+	return nil
+}
+
+func TestJwtToken_LegacyServerSeed(t *testing.T) {
+	defer sctx.RegisterServerImplementor(sctx.ServerImplementor())
+	sctx.RegisterServerImplementor(legacyServerSeed{
+		sctx.CurrentKeyID: []byte("TestJwtToken_LegacyServerSeed"),
+	})
+
+	j := &JwtToken{}
+	now := time.Now()
+	val, err := j.Generate(&secret.Info{
+		Type:      secret.Type_SERVER,
+		Issuer:    "appootb",
+		KeyId:     sctx.CurrentKeyID,
+		Subject:   permission.Subject_SERVER,
+		IssuedAt:  timestamppb.New(now),
+		ExpiredAt: timestamppb.New(now.Add(time.Hour)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, err := j.ParseRaw(val); err != nil || s.GetAlgorithm() != secret.Algorithm_HMAC {
+		t.Fatal(s, err)
+	}
+	if jwks, err := j.JWKS(); err != nil || string(jwks) != `{"keys":[]}` {
+		t.Fatal(string(jwks), err)
+	}
+}
+
+func TestJwtToken_RevokeSession(t *testing.T) {
+	j := &JwtToken{}
+	now := time.Now()
+	info := &secret.Info{
+		Type:      secret.Type_CLIENT,
+		Algorithm: secret.Algorithm_HMAC,
+		Issuer:    "appootb",
+		Account:   987654321,
+		KeyId:     hash.Sum("TestJwtToken_RevokeSession"),
+		Subject:   permission.Subject_MOBILE,
+		IssuedAt:  timestamppb.New(now),
+		ExpiredAt: timestamppb.New(now.Add(time.Hour)),
+	}
+	token, err := j.GenerateWithMetadata(info, &common.Metadata{
+		Platform: common.Platform_PLATFORM_ANDROID,
+		DeviceId: "device-1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions, err := sctx.SessionImplementor().ListSessions(info.Account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].KeyID != info.KeyId || sessions[0].DeviceID != "device-1" {
+		t.Fatal("bad sessions", sessions)
+	}
+	if _, err = j.ParseRaw(token); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = sctx.SessionImplementor().RevokeSession(info.Account, info.KeyId); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = j.ParseRaw(token); err == nil {
+		t.Fatal("revoked token accepted")
+	}
+	if sessions, _ = sctx.SessionImplementor().ListSessions(info.Account); len(sessions) != 0 {
+		t.Fatal("revoked session listed")
+	}
+
+	// Signed in again with the same key ID.
+	if token, err = j.Generate(info); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = j.ParseRaw(token); err != nil {
+		t.Fatal("signed in again", err)
+	}
+}
+
+func TestJwtToken_RevokeSessionPair(t *testing.T) {
+	j := &JwtToken{}
+	now := time.Now()
+	info := &secret.Info{
+		Type:      secret.Type_CLIENT,
+		Algorithm: secret.Algorithm_HMAC,
+		Issuer:    "appootb",
+		Account:   918273645,
+		KeyId:     hash.Sum("TestJwtToken_RevokeSessionPair"),
+		Subject:   permission.Subject_MOBILE,
+		IssuedAt:  timestamppb.New(now),
+		ExpiredAt: timestamppb.New(now.Add(time.Minute)),
+	}
+	pair, err := j.GeneratePair(info, &common.Metadata{DeviceId: "device-1"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = sctx.SessionImplementor().RevokeSession(info.Account, info.KeyId); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = j.RefreshPair(pair.RefreshToken); err == nil {
+		t.Fatal("refresh token of the revoked session accepted")
+	}
+	if sessions, _ := sctx.SessionImplementor().ListSessions(info.Account); len(sessions) != 0 {
+		t.Fatal("revoked session added back", sessions)
+	}
+}
+
+func TestJwtToken_RefreshPair(t *testing.T) {
+	j := &JwtToken{}
+	if _, ok := token.Token(j).(token.PairGenerator); !ok {
+		t.Fatal("refresh token pairs not supported")
+	}
+	now := time.Now()
+	pair, err := j.GeneratePair(&secret.Info{
+		Type:      secret.Type_CLIENT,
+		Algorithm: secret.Algorithm_HMAC,
+		Issuer:    "appootb",
+		Account:   192837465,
+		KeyId:     hash.Sum("TestJwtToken_RefreshPair"),
+		Subject:   permission.Subject_MOBILE,
+		IssuedAt:  timestamppb.New(now),
+		ExpiredAt: timestamppb.New(now.Add(time.Minute)),
+	}, &common.Metadata{DeviceId: "device-1"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := j.RefreshPair(pair.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.RefreshToken == pair.RefreshToken {
+		t.Fatal("refresh token not rotated")
+	}
+	if _, err = j.ParseRaw(pair.AccessToken); err == nil {
+		t.Fatal("previous access token accepted")
+	}
+	if _, err = j.ParseRaw(rotated.AccessToken); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reuse the rotated refresh token.
+	if _, err = j.RefreshPair(pair.RefreshToken); err == nil {
+		t.Fatal("reused refresh token accepted")
+	}
+	if _, err = j.ParseRaw(rotated.AccessToken); err == nil {
+		t.Fatal("access token not revoked on reuse")
+	}
+	if _, err = j.RefreshPair(rotated.RefreshToken); err == nil {
+		t.Fatal("refresh token family not revoked on reuse")
+	}
+}
+
+func TestJwtToken_AlgorithmConfusion(t *testing.T) {
+	j := &JwtToken{}
+	keyID := hash.Sum("TestJwtToken_AlgorithmConfusion")
+	key, err := j.NewSecretKey(secret.Algorithm_RSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = sctx.ServerImplementor().Add(keyID, key); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := j.JWKS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var set jwk.Set
+	if err = json.Unmarshal(buf, &set); err != nil {
+		t.Fatal(err)
+	}
+	pub, err := set.Find(strconv.FormatInt(keyID, 10)).PKIX()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// HS512 token signed with the published public key.
+	now := time.Now()
+	forged, err := jwt.Sign(&jwt.Payload{
+		Subject:        permission.Subject_SERVER.String(),
+		ExpirationTime: jwt.NumericDate(now.Add(time.Hour)),
+		NotBefore:      jwt.NumericDate(now.Add(-time.Minute)),
+		IssuedAt:       jwt.NumericDate(now),
+	}, jwt.NewHS512(pub), jwt.ContentType(secret.Type_SERVER.String()), jwt.KeyID(fmt.Sprintf("1-%d", keyID)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = j.ParseRaw(string(forged)); err == nil {
+		t.Fatal("forged HMAC token accepted by the private key")
+	}
+	public := &credential.PublicSeed{}
+	if err = public.Add(keyID, key); err != nil {
+		t.Fatal(err)
+	}
+	signer := sctx.ServerImplementor()
+	defer sctx.RegisterServerImplementor(signer)
+	sctx.RegisterServerImplementor(public)
+	if _, err = j.ParseRaw(string(forged)); err == nil {
+		t.Fatal("forged HMAC token accepted by the public key")
+	}
+}
+
+func TestJwtToken_EdDSAServerKey(t *testing.T) {
+	j := &JwtToken{}
+	keyID := hash.Sum("TestJwtToken_EdDSAServerKey")
+	key, err := j.NewSecretKey(secret.Algorithm_EdDSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sctx.ServerImplementor().(sctx.ServerKeyStore).AddKey(&sctx.ServerKey{
+		ID:        keyID,
+		Key:       key,
+		Algorithm: secret.Algorithm_EdDSA,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	token, err := j.Generate(&secret.Info{
+		Type:      secret.Type_SERVER,
+		Algorithm: secret.Algorithm_HMAC,
+		Issuer:    "appootb",
+		KeyId:     keyID,
+		Subject:   permission.Subject_SERVER,
+		IssuedAt:  timestamppb.New(now),
+		ExpiredAt: timestamppb.New(now.Add(time.Hour)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := j.ParseRaw(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.GetAlgorithm() != secret.Algorithm_EdDSA {
+		t.Fatal("signed with", s.GetAlgorithm())
+	}
+}
+
+func TestJwtToken_RefreshPairConcurrent(t *testing.T) {
+	j := &JwtToken{}
+	now := time.Now()
+	pair, err := j.GeneratePair(&secret.Info{
+		Type:      secret.Type_CLIENT,
+		Algorithm: secret.Algorithm_HMAC,
+		Issuer:    "appootb",
+		Account:   564738291,
+		KeyId:     hash.Sum("TestJwtToken_RefreshPairConcurrent"),
+		Subject:   permission.Subject_MOBILE,
+		IssuedAt:  timestamppb.New(now),
+		ExpiredAt: timestamppb.New(now.Add(time.Minute)),
+	}, &common.Metadata{DeviceId: "device-1"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		rotated []*token.Pair
+		reused  int
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p, err := j.RefreshPair(pair.RefreshToken)
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				rotated = append(rotated, p)
+			} else if status.Convert(err).Message() == "substratum: refresh token reused" {
+				reused++
+			}
+		}()
+	}
+	wg.Wait()
+	if len(rotated) != 1 || reused == 0 {
+		t.Fatal("rotated:", len(rotated), "reused:", reused)
+	}
+	// The family expires with the first refresh token.
+	if !rotated[0].RefreshExpiredAt.Equal(pair.RefreshExpiredAt) {
+		t.Fatal("family expiration extended", rotated[0].RefreshExpiredAt, pair.RefreshExpiredAt)
+	}
+}
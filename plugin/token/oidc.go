@@ -0,0 +1,374 @@
+package token
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/appootb/substratum/v2/proto/go/common"
+	"github.com/appootb/substratum/v2/proto/go/permission"
+	"github.com/appootb/substratum/v2/proto/go/secret"
+	"github.com/appootb/substratum/v2/util/jwk"
+	"github.com/gbrlsnchs/jwt/v3"
+	"github.com/gbrlsnchs/jwt/v3/jwtutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	OIDCDiscoveryPath = "/.well-known/openid-configuration"
+	// Minimum interval of fetching the issuer key set for unknown key IDs, or after a failed fetching.
+	minOIDCFetchInterval = time.Minute
+)
+
+// AccountResolver returns the account ID of the token claims.
+type AccountResolver func(claims map[string]interface{}) (uint64, error)
+
+type OIDCOption func(*OIDCParser)
+
+// WithAudience sets the accepted audiences, the audience is not verified if not set.
+func WithAudience(aud ...string) OIDCOption {
+	return func(p *OIDCParser) {
+		p.audience = aud
+	}
+}
+
+// WithJWKSURL sets the key set URL of the issuer instead of the discovered one.
+func WithJWKSURL(url string) OIDCOption {
+	return func(p *OIDCParser) {
+		p.jwksURL = url
+	}
+}
+
+// WithJWKSFile loads the key set from a local file instead of the issuer, for offline tests.
+func WithJWKSFile(path string) OIDCOption {
+	return func(p *OIDCParser) {
+		p.jwksFile = path
+	}
+}
+
+// WithAccountClaim sets the numeric claim of the account ID, defaults to `sub`.
+func WithAccountClaim(claim string) OIDCOption {
+	return func(p *OIDCParser) {
+		p.account = ClaimAccountResolver(claim)
+	}
+}
+
+// WithAccountResolver sets the resolver of the account ID, e.g. mapping the external subject to a local account.
+func WithAccountResolver(resolver AccountResolver) OIDCOption {
+	return func(p *OIDCParser) {
+		p.account = resolver
+	}
+}
+
+// WithRolesClaim sets the claim of the roles, defaults to `roles`.
+// The claim is an array of strings, or a space separated string like `scope`.
+func WithRolesClaim(claim string) OIDCOption {
+	return func(p *OIDCParser) {
+		p.rolesClaim = claim
+	}
+}
+
+// WithSubject sets the subject of the tokens, defaults to permission.Subject_LOGGED_IN.
+func WithSubject(sub permission.Subject) OIDCOption {
+	return func(p *OIDCParser) {
+		p.subject = sub
+	}
+}
+
+// ClaimAccountResolver returns the account ID of a numeric claim.
+func ClaimAccountResolver(claim string) AccountResolver {
+	return func(claims map[string]interface{}) (uint64, error) {
+		switch v := claims[claim].(type) {
+		case string:
+			return strconv.ParseUint(v, 10, 64)
+		case json.Number:
+			return strconv.ParseUint(v.String(), 10, 64)
+		default:
+			return 0, fmt.Errorf("substratum: account claim %s not found", claim)
+		}
+	}
+}
+
+// OIDCParser verifies the ID/access tokens issued by a third-party OpenID Connect provider,
+// the key set of the issuer is fetched once and cached, and refreshed on unknown key IDs.
+// The fetching is rate limited by minOIDCFetchInterval, including the failed initial ones.
+type OIDCParser struct {
+	issuer     string
+	audience   []string
+	jwksURL    string
+	jwksFile   string
+	account    AccountResolver
+	rolesClaim string
+	subject    permission.Subject
+	client     *http.Client
+
+	mu        sync.Mutex
+	keySet    *jwk.Set
+	fetchedAt time.Time
+	fetching  chan struct{} // Closed when the fetching in progress completes.
+}
+
+func NewOIDCParser(issuer string, opts ...OIDCOption) *OIDCParser {
+	p := &OIDCParser{
+		issuer:     strings.TrimSuffix(issuer, "/"),
+		account:    ClaimAccountResolver("sub"),
+		rolesClaim: "roles",
+		subject:    permission.Subject_LOGGED_IN,
+		client:     &http.Client{Timeout: time.Second * 5},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Parse the bearer token of the request metadata.
+func (p *OIDCParser) Parse(md *common.Metadata) (*secret.Info, error) {
+	return p.ParseRaw(strings.TrimPrefix(md.GetToken(), "Bearer "))
+}
+
+// ParseRaw parses a token string.
+func (p *OIDCParser) ParseRaw(token string) (*secret.Info, error) {
+	var (
+		now     = time.Now()
+		alg     secret.Algorithm
+		payload jwt.Payload
+		claims  map[string]interface{}
+	)
+	resolver := &jwtutil.Resolver{
+		New: func(header jwt.Header) (jwt.Algorithm, error) {
+			key, err := p.publicKey(header.KeyID)
+			if err != nil {
+				return nil, err
+			}
+			var algorithm jwt.Algorithm
+			alg, algorithm = p.algorithm(header.Algorithm, key)
+			if algorithm == nil {
+				return nil, jwt.ErrAlgValidation
+			}
+			return algorithm, nil
+		},
+	}
+	validators := []jwt.Validator{
+		jwt.IssuerValidator(p.issuer),
+		jwt.ExpirationTimeValidator(now),
+		jwt.NotBeforeValidator(now),
+	}
+	if len(p.audience) > 0 {
+		validators = append(validators, jwt.AudienceValidator(p.audience))
+	}
+	_, err := jwt.Verify([]byte(token), resolver, &payload, jwt.ValidateHeader, jwt.ValidatePayload(&payload, validators...))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "substratum: verify oidc token failed: "+err.Error())
+	}
+	if payload.ExpirationTime == nil {
+		return nil, status.Error(codes.Unauthenticated, "substratum: oidc token expiration required")
+	}
+	if claims, err = p.claims(token); err != nil {
+		return nil, status.Error(codes.Unauthenticated, "substratum: invalid oidc token claims")
+	}
+	account, err := p.account(claims)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	issuedAt := now
+	if payload.IssuedAt != nil {
+		issuedAt = payload.IssuedAt.Time
+	}
+	return &secret.Info{
+		Type:      secret.Type_CLIENT,
+		Algorithm: alg,
+		Issuer:    payload.Issuer,
+		Account:   account,
+		Roles:     p.roles(claims),
+		Subject:   p.subject,
+		IssuedAt:  timestamppb.New(issuedAt),
+		ExpiredAt: timestamppb.New(payload.ExpirationTime.Time),
+	}, nil
+}
+
+// claims decodes all the claims of the verified token, numbers are decoded as json.Number.
+func (p *OIDCParser) claims(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, jwt.ErrMalformed
+	}
+	buf, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	var claims map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(buf))
+	decoder.UseNumber()
+	if err = decoder.Decode(&claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (p *OIDCParser) roles(claims map[string]interface{}) []string {
+	roles := []string{}
+	switch v := claims[p.rolesClaim].(type) {
+	case string:
+		roles = append(roles, strings.Fields(v)...)
+	case []interface{}:
+		for _, role := range v {
+			if s, ok := role.(string); ok {
+				roles = append(roles, s)
+			}
+		}
+	}
+	return roles
+}
+
+// algorithm returns the verify-only algorithm matching the key type, symmetric algorithms are not accepted.
+func (p *OIDCParser) algorithm(name string, key interface{}) (secret.Algorithm, jwt.Algorithm) {
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		switch name {
+		case "RS256":
+			return secret.Algorithm_RSA, jwt.NewRS256(jwt.RSAPublicKey(pub))
+		case "RS384":
+			return secret.Algorithm_RSA, jwt.NewRS384(jwt.RSAPublicKey(pub))
+		case "RS512":
+			return secret.Algorithm_RSA, jwt.NewRS512(jwt.RSAPublicKey(pub))
+		case "PS256":
+			return secret.Algorithm_PSS, jwt.NewPS256(jwt.RSAPublicKey(pub))
+		case "PS384":
+			return secret.Algorithm_PSS, jwt.NewPS384(jwt.RSAPublicKey(pub))
+		case "PS512":
+			return secret.Algorithm_PSS, jwt.NewPS512(jwt.RSAPublicKey(pub))
+		}
+	case *ecdsa.PublicKey:
+		switch name {
+		case "ES256":
+			return secret.Algorithm_ECDSA, jwt.NewES256(jwt.ECDSAPublicKey(pub))
+		case "ES384":
+			return secret.Algorithm_ECDSA, jwt.NewES384(jwt.ECDSAPublicKey(pub))
+		case "ES512":
+			return secret.Algorithm_ECDSA, jwt.NewES512(jwt.ECDSAPublicKey(pub))
+		}
+	case ed25519.PublicKey:
+		if name == "EdDSA" {
+			return secret.Algorithm_EdDSA, jwt.NewEd25519(jwt.Ed25519PublicKey(pub))
+		}
+	}
+	return secret.Algorithm_None, nil
+}
+
+// publicKey returns the public key of the issuer, the key set is refreshed if not found.
+func (p *OIDCParser) publicKey(kid string) (interface{}, error) {
+	keySet, err := p.keys(kid)
+	if err != nil {
+		return nil, err
+	}
+	key := keySet.Find(kid)
+	if key == nil {
+		return nil, fmt.Errorf("substratum: oidc key %s not found", kid)
+	}
+	pkix, err := key.PKIX()
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParsePKIXPublicKey(pkix)
+}
+
+// keys returns the key set containing the key ID if fetched, the lock is not held while fetching,
+// and the concurrent lookups wait for the fetching in progress.
+func (p *OIDCParser) keys(kid string) (*jwk.Set, error) {
+	p.mu.Lock()
+	if fetching := p.fetching; fetching != nil {
+		p.mu.Unlock()
+		<-fetching
+		p.mu.Lock()
+	}
+	keySet := p.keySet
+	if (keySet == nil || keySet.Find(kid) == nil) && p.fetching == nil &&
+		time.Since(p.fetchedAt) > minOIDCFetchInterval {
+		fetching := make(chan struct{})
+		p.fetching, p.fetchedAt = fetching, time.Now()
+		p.mu.Unlock()
+		set, err := p.fetch()
+		p.mu.Lock()
+		if err == nil {
+			p.keySet = set
+		}
+		p.fetching = nil
+		close(fetching)
+		p.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		return set, nil
+	}
+	p.mu.Unlock()
+	if keySet == nil {
+		return nil, fmt.Errorf("substratum: oidc key set of issuer %s not fetched", p.issuer)
+	}
+	return keySet, nil
+}
+
+func (p *OIDCParser) fetch() (*jwk.Set, error) {
+	var (
+		buf []byte
+		err error
+	)
+	if p.jwksFile != "" {
+		buf, err = ioutil.ReadFile(p.jwksFile)
+	} else {
+		buf, err = p.fetchJWKS()
+	}
+	if err != nil {
+		return nil, err
+	}
+	var set jwk.Set
+	if err = json.Unmarshal(buf, &set); err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+func (p *OIDCParser) fetchJWKS() ([]byte, error) {
+	if p.jwksURL == "" {
+		var cfg struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+		buf, err := p.get(p.issuer + OIDCDiscoveryPath)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(buf, &cfg); err != nil {
+			return nil, err
+		}
+		if cfg.JWKSURI == "" {
+			return nil, fmt.Errorf("substratum: jwks_uri not found of issuer %s", p.issuer)
+		}
+		p.jwksURL = cfg.JWKSURI
+	}
+	return p.get(p.jwksURL)
+}
+
+func (p *OIDCParser) get(url string) ([]byte, error) {
+	resp, err := p.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
@@ -0,0 +1,178 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/appootb/substratum/v2/auth"
+	"github.com/appootb/substratum/v2/proto/go/common"
+	"github.com/appootb/substratum/v2/proto/go/permission"
+	"github.com/appootb/substratum/v2/proto/go/secret"
+	"github.com/appootb/substratum/v2/util/hash"
+	"github.com/appootb/substratum/v2/util/jwk"
+	"github.com/gbrlsnchs/jwt/v3"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type oidcClaims struct {
+	jwt.Payload
+	Roles []string `json:"roles"`
+}
+
+func TestOIDCParser_Parse(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkix, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := jwk.New("oidc-key", pkix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, _ := json.Marshal(&jwk.Set{Keys: []*jwk.Key{key}})
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	if err = ioutil.WriteFile(jwksFile, buf, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	oidcToken, err := jwt.Sign(&oidcClaims{
+		Payload: jwt.Payload{
+			Issuer:         "https://issuer.example.com",
+			Subject:        "123456789",
+			Audience:       jwt.Audience{"app"},
+			ExpirationTime: jwt.NumericDate(now.Add(time.Hour)),
+			IssuedAt:       jwt.NumericDate(now),
+		},
+		Roles: []string{"editor"},
+	}, jwt.NewRS256(jwt.RSAPrivateKey(priv)), jwt.KeyID("oidc-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewOIDCParser("https://issuer.example.com/", WithJWKSFile(jwksFile), WithAudience("app"))
+	s, err := p.Parse(&common.Metadata{Token: "Bearer " + string(oidcToken)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.GetAccount() != 123456789 || s.GetRoles()[0] != "editor" || s.GetSubject() != permission.Subject_LOGGED_IN {
+		t.Fatal("bad secret info", s)
+	}
+	// Audience mismatch.
+	if _, err = NewOIDCParser("https://issuer.example.com", WithJWKSFile(jwksFile),
+		WithAudience("other")).Parse(&common.Metadata{Token: string(oidcToken)}); err == nil {
+		t.Fatal("audience not verified")
+	}
+
+	// Chained with the self-issued JWT parser.
+	j := &JwtToken{}
+	jwtToken, err := j.Generate(&secret.Info{
+		Type:      secret.Type_CLIENT,
+		Algorithm: secret.Algorithm_HMAC,
+		Issuer:    "appootb",
+		Account:   987654321,
+		KeyId:     hash.Sum("TestOIDCParser_Parse"),
+		Subject:   permission.Subject_MOBILE,
+		IssuedAt:  timestamppb.New(now),
+		ExpiredAt: timestamppb.New(now.Add(time.Hour)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := auth.ChainTokenParser(j, p)
+	if s, err = chain.Parse(&common.Metadata{Token: jwtToken}); err != nil || s.GetAccount() != 987654321 {
+		t.Fatal(s, err)
+	}
+	if s, err = chain.Parse(&common.Metadata{Token: string(oidcToken)}); err != nil || s.GetAccount() != 123456789 {
+		t.Fatal(s, err)
+	}
+}
+
+func TestOIDCParser_Fetch(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkix, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := jwk.New("oidc-key", pkix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, _ := json.Marshal(&jwk.Set{Keys: []*jwk.Key{key}})
+	now := time.Now()
+	oidcToken, err := jwt.Sign(&jwt.Payload{
+		Issuer:         "https://issuer.example.com",
+		Subject:        "123456789",
+		ExpirationTime: jwt.NumericDate(now.Add(time.Hour)),
+	}, jwt.NewRS256(jwt.RSAPrivateKey(priv)), jwt.KeyID("oidc-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		requests int32
+		failed   = true
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if failed {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+		_, _ = w.Write(buf)
+	}))
+	defer srv.Close()
+
+	// Failed initial fetching is rate limited.
+	p := NewOIDCParser("https://issuer.example.com", WithJWKSURL(srv.URL))
+	for i := 0; i < 3; i++ {
+		if _, err = p.ParseRaw(string(oidcToken)); err == nil {
+			t.Fatal("parsed without the key set")
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatal("unexpected fetching requests", n)
+	}
+
+	// Concurrent lookups share the fetching in progress.
+	failed = false
+	atomic.StoreInt32(&requests, 0)
+	p = NewOIDCParser("https://issuer.example.com", WithJWKSURL(srv.URL))
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := p.ParseRaw(string(oidcToken))
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatal("unexpected fetching requests", n)
+	}
+}
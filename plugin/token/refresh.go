@@ -0,0 +1,193 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/appootb/substratum/v2/credential"
+	"github.com/appootb/substratum/v2/logger"
+	"github.com/appootb/substratum/v2/proto/go/common"
+	"github.com/appootb/substratum/v2/proto/go/secret"
+	"github.com/appootb/substratum/v2/token"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// refreshRecord is stored as the client key of the refresh token,
+// with a negative key ID to avoid conflicts with the access token keys.
+type refreshRecord struct {
+	Digest   []byte `json:"digest"`
+	Info     []byte `json:"info"`
+	Metadata []byte `json:"metadata,omitempty"`
+	// Absolute expiration of the token family, kept on rotation.
+	ExpiredAt time.Time `json:"expired_at"`
+	Used      bool      `json:"used,omitempty"`
+}
+
+// GeneratePair generates a new access token and a long-lived opaque refresh token.
+func (t *JwtToken) GeneratePair(s *secret.Info, md *common.Metadata, refreshTTL time.Duration) (*token.Pair, error) {
+	if s.GetType() == secret.Type_SERVER {
+		return nil, status.Error(codes.InvalidArgument, "substratum: refresh token not supported for server secret")
+	}
+	return t.generatePair(s, md, time.Now().Add(refreshTTL))
+}
+
+func (t *JwtToken) generatePair(s *secret.Info, md *common.Metadata, refreshExpiredAt time.Time) (*token.Pair, error) {
+	refreshToken, refreshID, err := t.newRefreshToken(s, md, refreshExpiredAt)
+	if err != nil {
+		return nil, err
+	}
+	// The refresh token is revoked with the session.
+	accessToken, err := t.generate(s, md, refreshID)
+	if err != nil {
+		_ = credential.ClientImplementor().Revoke(s.GetAccount(), refreshID)
+		return nil, err
+	}
+	return &token.Pair{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		AccessExpiredAt:  s.GetExpiredAt().AsTime(),
+		RefreshExpiredAt: refreshExpiredAt,
+	}, nil
+}
+
+// RefreshPair rotates the refresh token and generates a new access token,
+// all the keys of the account are revoked if a rotated refresh token is reused.
+// The rotated refresh tokens expire with the first one of the family.
+func (t *JwtToken) RefreshPair(refreshToken string) (*token.Pair, error) {
+	swapper, ok := credential.ClientImplementor().(credential.ClientSwapper)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "substratum: client seed does not support compare-and-swap")
+	}
+	accountID, refreshID, digest, err := t.parseRefreshToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	val, err := credential.ClientImplementor().Get(accountID, refreshID)
+	if err != nil {
+		return nil, err
+	}
+	var record refreshRecord
+	if err = json.Unmarshal(val, &record); err != nil {
+		return nil, status.Error(codes.Unauthenticated, "substratum: invalid refresh token")
+	}
+	if subtle.ConstantTimeCompare(record.Digest, digest) != 1 {
+		return nil, status.Error(codes.Unauthenticated, "substratum: invalid refresh token")
+	}
+	var (
+		s  secret.Info
+		md common.Metadata
+	)
+	if err = proto.Unmarshal(record.Info, &s); err != nil {
+		return nil, err
+	}
+	if err = proto.Unmarshal(record.Metadata, &md); err != nil {
+		return nil, err
+	}
+	// The refresh token is revoked with the session, in case it is not revoked by the session store.
+	if err = t.checkRevoked(accountID, s.GetKeyId()); err != nil {
+		_ = credential.ClientImplementor().Revoke(accountID, refreshID)
+		return nil, err
+	}
+	// Rotate, keep the used record for reuse detection until it expires.
+	// The swap fails if the token is used concurrently.
+	rotated := !record.Used
+	if rotated {
+		record.Used = true
+		if rotated, err = t.swapRefreshRecord(swapper, accountID, refreshID, val, &record); err != nil {
+			return nil, err
+		}
+	}
+	if !rotated {
+		// The token family is compromised.
+		logger.Warn("substratum refresh token reused", logger.Content{
+			"account": accountID,
+		})
+		if err = credential.ClientImplementor().RevokeAll(accountID); err != nil {
+			return nil, err
+		}
+		return nil, status.Error(codes.Unauthenticated, "substratum: refresh token reused")
+	}
+	// Renew the access token, the previous one is invalidated by the new key of the same key ID.
+	now := time.Now()
+	s.ExpiredAt = timestamppb.New(now.Add(s.GetExpiredAt().AsTime().Sub(s.GetIssuedAt().AsTime())))
+	s.IssuedAt = timestamppb.New(now)
+	return t.generatePair(&s, &md, record.ExpiredAt)
+}
+
+// newRefreshToken stores a new refresh record, returns the refresh token and its key ID.
+func (t *JwtToken) newRefreshToken(s *secret.Info, md *common.Metadata, expiredAt time.Time) (string, int64, error) {
+	var buf [40]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return "", 0, err
+	}
+	refreshID := -int64(binary.BigEndian.Uint64(buf[:8])>>2) - 1
+	secretKey := base64.RawURLEncoding.EncodeToString(buf[8:])
+	digest := sha256.Sum256([]byte(secretKey))
+	info, err := proto.Marshal(s)
+	if err != nil {
+		return "", 0, err
+	}
+	mdBytes, err := proto.Marshal(md)
+	if err != nil {
+		return "", 0, err
+	}
+	ttl := time.Until(expiredAt)
+	if ttl <= 0 {
+		return "", 0, status.Error(codes.Unauthenticated, "substratum: refresh token expired")
+	}
+	val, err := json.Marshal(&refreshRecord{
+		Digest:    digest[:],
+		Info:      info,
+		Metadata:  mdBytes,
+		ExpiredAt: expiredAt,
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	if err = credential.ClientImplementor().Add(s.GetAccount(), refreshID, val, ttl); err != nil {
+		return "", 0, err
+	}
+	return fmt.Sprintf("%d.%d.%s", s.GetAccount(), -refreshID, secretKey), refreshID, nil
+}
+
+// swapRefreshRecord replaces the stored record atomically, returns false if it is changed concurrently.
+func (t *JwtToken) swapRefreshRecord(swapper credential.ClientSwapper, accountID uint64, refreshID int64,
+	old []byte, record *refreshRecord) (bool, error) {
+	ttl := time.Until(record.ExpiredAt)
+	if ttl <= 0 {
+		return false, status.Error(codes.Unauthenticated, "substratum: refresh token expired")
+	}
+	val, err := json.Marshal(record)
+	if err != nil {
+		return false, err
+	}
+	return swapper.CompareAndSwap(accountID, refreshID, old, val, ttl)
+}
+
+func (t *JwtToken) parseRefreshToken(refreshToken string) (uint64, int64, []byte, error) {
+	parts := strings.SplitN(refreshToken, ".", 3)
+	if len(parts) != 3 {
+		return 0, 0, nil, status.Error(codes.Unauthenticated, "substratum: invalid refresh token")
+	}
+	accountID, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, nil, status.Error(codes.Unauthenticated, "substratum: invalid refresh token")
+	}
+	refreshID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || refreshID <= 0 {
+		return 0, 0, nil, status.Error(codes.Unauthenticated, "substratum: invalid refresh token")
+	}
+	digest := sha256.Sum256([]byte(parts[2]))
+	return accountID, -refreshID, digest[:], nil
+}
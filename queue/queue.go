@@ -44,6 +44,12 @@ type Queue interface {
 	Subscribe(topic string, handler Consumer, opts ...SubscribeOption) error
 }
 
+// Shutdowner is implemented by the queues consuming in background, detected by type assertion.
+type Shutdowner interface {
+	// Shutdown stops consuming and waits for the in-flight messages to be processed.
+	Shutdown(ctx context.Context) error
+}
+
 type PublishOption func(*PublishOptions)
 
 var EmptyPublishOptions = func() *PublishOptions {
@@ -12,6 +12,7 @@ import (
 	"github.com/appootb/substratum/v2/monitor"
 	"github.com/appootb/substratum/v2/queue"
 	"github.com/appootb/substratum/v2/recovery"
+	"github.com/appootb/substratum/v2/snowflake"
 	"github.com/appootb/substratum/v2/storage"
 	"github.com/appootb/substratum/v2/task"
 	"google.golang.org/grpc"
@@ -78,9 +79,11 @@ func WithDefaultUnaryInterceptors(fns ...grpc.UnaryServerInterceptor) ServerOpti
 			auth.UnaryServerInterceptor(),
 			logger.UnaryServerInterceptor(),
 			validator.UnaryServerInterceptor(),
+			auth.UnaryOwnershipInterceptor(),
 			client.UnaryServerInterceptor(),
 			discovery.UnaryServerInterceptor(),
 			storage.UnaryServerInterceptor(),
+			snowflake.UnaryServerInterceptor(),
 			queue.UnaryServerInterceptor(),
 			task.UnaryServerInterceptor(),
 		)
@@ -104,9 +107,11 @@ func WithDefaultStreamInterceptors(fns ...grpc.StreamServerInterceptor) ServerOp
 			auth.StreamServerInterceptor(),
 			logger.StreamServerInterceptor(),
 			validator.StreamServerInterceptor(),
+			auth.StreamOwnershipInterceptor(),
 			client.StreamServerInterceptor(),
 			discovery.StreamServerInterceptor(),
 			storage.StreamServerInterceptor(),
+			snowflake.StreamServerInterceptor(),
 			queue.StreamServerInterceptor(),
 			task.StreamServerInterceptor(),
 		)
@@ -3,29 +3,47 @@ package substratum
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/appootb/substratum/v2/auth"
+	"github.com/appootb/substratum/v2/client"
 	"github.com/appootb/substratum/v2/configure"
+	sctx "github.com/appootb/substratum/v2/context"
 	"github.com/appootb/substratum/v2/discovery"
 	ictx "github.com/appootb/substratum/v2/internal/context"
+	"github.com/appootb/substratum/v2/logger"
 	"github.com/appootb/substratum/v2/plugin"
 	"github.com/appootb/substratum/v2/proto/go/permission"
 	"github.com/appootb/substratum/v2/queue"
 	"github.com/appootb/substratum/v2/rpc"
 	"github.com/appootb/substratum/v2/server"
+	"github.com/appootb/substratum/v2/snowflake"
 	"github.com/appootb/substratum/v2/storage"
 	"github.com/appootb/substratum/v2/task"
-	"github.com/appootb/substratum/v2/util/snowflake"
+	"github.com/appootb/substratum/v2/token"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 type Server struct {
-	ctx          context.Context
-	keepAliveTTL time.Duration
+	ctx             context.Context
+	keepAliveTTL    time.Duration
+	healthInterval  time.Duration
+	shutdownTimeout time.Duration
+
+	quit        chan struct{}
+	shutdown    sync.Once
+	shutdownErr error
 
 	components  []Component
+	started     int // Number of the components started.
 	rpcServices map[string][]string
 	serveMuxers map[permission.VisibleScope]*server.ServeMux
 }
@@ -35,10 +53,13 @@ func NewServer(opts ...ServerOption) Service {
 	plugin.Register()
 	// New server
 	srv := &Server{
-		ctx:          ictx.Context,
-		keepAliveTTL: 3 * time.Second,
-		rpcServices:  make(map[string][]string),
-		serveMuxers:  make(map[permission.VisibleScope]*server.ServeMux),
+		ctx:             ictx.Context,
+		keepAliveTTL:    3 * time.Second,
+		healthInterval:  5 * time.Second,
+		shutdownTimeout: 30 * time.Second,
+		quit:            make(chan struct{}),
+		rpcServices:     make(map[string][]string),
+		serveMuxers:     make(map[permission.VisibleScope]*server.ServeMux),
 	}
 	opts = append(opts, WithDefaultClientMux(), WithDefaultServerMux())
 	for _, opt := range opts {
@@ -96,16 +117,29 @@ func (s *Server) GetGatewayMux(scope permission.VisibleScope) []*runtime.ServeMu
 	return srv
 }
 
-func (s *Server) AddServeMux(scope permission.VisibleScope, rpcPort, gatewayPort uint16) error {
+func (s *Server) AddServeMux(scope permission.VisibleScope, rpcPort, gatewayPort uint16, opts ...server.Option) error {
 	var err error
 	if _, ok := s.serveMuxers[scope]; ok {
 		return errors.New("ServerMux for the specified scope has already been registered")
 	}
 	metrics := scope == permission.VisibleScope_SERVER
-	s.serveMuxers[scope], err = server.NewServeMux(rpcPort, gatewayPort, metrics)
+	opts = append([]server.Option{server.WithScope(scope)}, opts...)
+	s.serveMuxers[scope], err = server.NewServeMux(rpcPort, gatewayPort, metrics, opts...)
 	if err != nil {
 		return err
 	}
+	// Publish the public server keys for the peers verifying tokens.
+	if ks, ok := token.Implementor().(token.KeySet); ok && scope == permission.VisibleScope_SERVER {
+		s.serveMuxers[scope].HTTPMux("").HandleFunc(token.JWKSPath, func(w http.ResponseWriter, _ *http.Request) {
+			buf, err := ks.JWKS()
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(buf)
+		})
+	}
 	return nil
 }
 
@@ -133,16 +167,36 @@ func (s *Server) Register(comp Component, rpcs ...string) error {
 }
 
 func (s *Server) Serve(isolate ...bool) error {
+	// Allocate snowflake partitions, the node-level one is shared by the process.
+	if _, err := snowflake.Implementor().Allocate("", s.keepAliveTTL); err != nil {
+		return s.abort(err)
+	}
+	for _, comp := range s.components {
+		if _, err := snowflake.Implementor().Allocate(comp.Name(), s.keepAliveTTL); err != nil {
+			return s.abort(err)
+		}
+	}
+
 	// Start queue worker and cron tasks.
 	for _, comp := range s.components {
 		if err := comp.RunQueueWorker(queue.Implementor()); err != nil {
-			return err
+			return s.abort(err)
 		}
 		if err := comp.ScheduleCronTask(task.Implementor()); err != nil {
-			return err
+			return s.abort(err)
 		}
 	}
 
+	// Start components.
+	for _, comp := range s.components {
+		if starter, ok := comp.(Starter); ok {
+			if err := starter.Start(sctx.ServerContext(comp.Name())); err != nil {
+				return s.abort(err)
+			}
+		}
+		s.started++
+	}
+
 	// Serve muxers.
 	for _, mux := range s.serveMuxers {
 		mux.Serve()
@@ -151,21 +205,164 @@ func (s *Server) Serve(isolate ...bool) error {
 	// Register node.
 	addr := s.serveMuxers[permission.VisibleScope_SERVER].ConnAddr()
 	for _, comp := range s.components {
-		nodeID, err := discovery.Implementor().Register(comp.Name(), addr,
+		_, err := discovery.Implementor().Register(comp.Name(), addr,
 			discovery.WithIsolate(len(isolate) > 0 && isolate[0]),
 			discovery.WithTTL(s.keepAliveTTL),
 			discovery.WithServices(s.rpcServices[comp.Name()]))
 		if err != nil {
-			return err
+			return s.abort(err)
 		}
-		// TODO:
-		// NodeID is unique in component scope on different node.
-		// If multiple components are registered within an unique server,
-		// snowflake's node ID might be the same on different nodes.
-		snowflake.SetPartitionID(nodeID)
 	}
 
-	// Wait for cancellation.
-	<-s.ctx.Done()
-	return nil
+	// Ready to serve.
+	go s.reportHealth()
+
+	// Wait for cancellation or termination signal.
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(sig)
+
+	select {
+	case <-s.ctx.Done():
+	case <-sig:
+	case <-s.quit:
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+	return s.Shutdown(ctx)
+}
+
+// Health reports the health status of the registered components,
+// the value is nil if the component is healthy.
+func (s *Server) Health(ctx context.Context) map[string]error {
+	health := make(map[string]error, len(s.components))
+	for _, comp := range s.components {
+		if checker, ok := comp.(HealthChecker); ok {
+			health[comp.Name()] = checker.Health(ctx)
+		} else {
+			health[comp.Name()] = nil
+		}
+	}
+	return health
+}
+
+func (s *Server) reportHealth() {
+	ticker := time.NewTicker(s.healthInterval)
+	defer ticker.Stop()
+
+	for {
+		s.updateServingStatus()
+
+		select {
+		case <-s.quit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) updateServingStatus() {
+	health := s.Health(s.ctx)
+	for _, mux := range s.serveMuxers {
+		ready := true
+		for name, info := range mux.RPCServer().GetServiceInfo() {
+			if name == healthpb.Health_ServiceDesc.ServiceName || len(info.Methods) == 0 {
+				continue
+			}
+			component := auth.Implementor().ServiceComponentName(fmt.Sprintf("/%s/%s", name, info.Methods[0].Name))
+			serving := health[component] == nil
+			mux.SetServingStatus(name, serving)
+			ready = ready && serving
+		}
+		mux.SetServingStatus("", ready)
+	}
+}
+
+// Shutdown deregisters the node, drains in-flight requests
+// and stops the queue workers and cron tasks.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.shutdown.Do(func() {
+		close(s.quit)
+		s.shutdownErr = s.gracefulStop(ctx)
+	})
+	return s.shutdownErr
+}
+
+// abort shuts down the partially started server and returns the serving error.
+func (s *Server) abort(err error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+	_ = s.Shutdown(ctx)
+	return err
+}
+
+func (s *Server) gracefulStop(ctx context.Context) error {
+	var firstErr error
+	report := func(stage string, err error) {
+		if err == nil {
+			return
+		}
+		logger.Error("substratum shutdown failed", logger.Content{
+			"stage": stage,
+			"error": err.Error(),
+		})
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	// Not ready, stop routing new requests to this node.
+	for _, mux := range s.serveMuxers {
+		mux.SetNotServing()
+	}
+	// Deregister node.
+	if deregisterer, ok := discovery.Implementor().(discovery.Deregisterer); ok {
+		for _, comp := range s.components {
+			report("discovery", deregisterer.Deregister(comp.Name()))
+		}
+	}
+
+	// Stop accepting and drain in-flight requests.
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, mux := range s.serveMuxers {
+		wg.Add(1)
+		go func(mux *server.ServeMux) {
+			defer wg.Done()
+			err := mux.Shutdown(ctx)
+			mu.Lock()
+			report("serve_mux", err)
+			mu.Unlock()
+		}(mux)
+	}
+	wg.Wait()
+
+	// Stop the started components in reverse order.
+	for i := s.started - 1; i >= 0; i-- {
+		if stopper, ok := s.components[i].(Stopper); ok {
+			report(s.components[i].Name(), stopper.Stop(ctx))
+		}
+	}
+
+	// Stop queue workers and cron tasks.
+	if shutdowner, ok := queue.Implementor().(queue.Shutdowner); ok {
+		report("queue", shutdowner.Shutdown(ctx))
+	}
+	if shutdowner, ok := task.Implementor().(task.Shutdowner); ok {
+		report("task", shutdowner.Shutdown(ctx))
+	}
+
+	// Release snowflake partitions, storage and client connections.
+	report("snowflake", snowflake.Implementor().Release())
+	if closer, ok := storage.Implementor().(storage.Closer); ok {
+		report("storage", closer.Close())
+	}
+	client.Implementor().Close()
+
+	// Cancel background routines.
+	ictx.Cancel()
+	return firstErr
 }
@@ -3,14 +3,33 @@ package server
 import (
 	"net/http"
 	"runtime/debug"
+	"strings"
 
+	"github.com/appootb/substratum/v2/auth"
 	sctx "github.com/appootb/substratum/v2/context"
+	"github.com/appootb/substratum/v2/gateway"
+	"github.com/appootb/substratum/v2/metadata"
+	"github.com/appootb/substratum/v2/proto/go/permission"
 	"github.com/appootb/substratum/v2/service"
+	"github.com/appootb/substratum/v2/util/jsonpb"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/status"
 )
 
+// HTTPAuthMethod returns the method name authenticating the http handler of the pattern,
+// e.g. `/_http/CLIENT/upload` for the pattern `/upload` of the client scope,
+// which is the key of the RBAC policy methods and the method overrides.
+func HTTPAuthMethod(scope permission.VisibleScope, pattern string) string {
+	return "/_http/" + scope.String() + "/" + strings.TrimPrefix(pattern, "/")
+}
+
 type handlerWrapper struct {
-	component string
-	handler   http.Handler
+	component  string
+	pattern    string
+	method     string
+	auth       bool
+	gatewayMux *runtime.ServeMux
+	handler    http.Handler
 }
 
 func (h *handlerWrapper) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -21,38 +40,84 @@ func (h *handlerWrapper) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 	//
-	ctx := service.ContextWithServiceMethod(r.Context(), &service.Method{
+	ctx := r.Context()
+	// Plain handlers are served without the incoming metadata if failed to annotate.
+	if annotated, err := runtime.AnnotateIncomingContext(ctx, h.gatewayMux, r, h.pattern); err == nil {
+		ctx = metadata.ContextWithIncomingMetadata(annotated)
+	} else if h.auth {
+		h.writeError(w, err)
+		return
+	}
+	ctx = service.ContextWithServiceMethod(ctx, &service.Method{
 		FullMethod:    r.URL.Path,
 		IsHttpGateway: true,
 	})
+	if h.auth {
+		secretInfo, err := auth.Implementor().Authenticate(ctx, h.method)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		ctx = service.ContextWithAccountSecret(ctx, secretInfo)
+	}
 	h.handler.ServeHTTP(w, r.WithContext(sctx.WithServerContext(ctx, h.component)))
 }
 
+func (h *handlerWrapper) writeError(w http.ResponseWriter, err error) {
+	s := status.Convert(err)
+	buf, err := jsonpb.Marshal(s.Proto())
+	if err != nil {
+		buf = []byte(`{"error": "failed to marshal error message"}`)
+	}
+	w.Header().Set("Content-Type", gateway.MIMEJSON)
+	w.WriteHeader(runtime.HTTPStatusFromCode(s.Code()))
+	_, _ = w.Write(buf)
+}
+
 type httpServeMux struct {
-	component string
-	serveMux  *http.ServeMux
+	component  string
+	scope      permission.VisibleScope
+	serveMux   *http.ServeMux
+	gatewayMux *runtime.ServeMux
 }
 
 func (h *httpServeMux) Handle(pattern string, handler http.Handler) {
-	h.serveMux.Handle(pattern, &handlerWrapper{
-		component: h.component,
-		handler:   handler,
-	})
+	h.HandleAuth(pattern, handler)
 }
 
 func (h *httpServeMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
-	h.serveMux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
-		defer func() {
-			if result := recover(); result != nil {
-				debug.PrintStack()
-				w.WriteHeader(http.StatusInternalServerError)
+	h.HandleAuth(pattern, http.HandlerFunc(handler))
+}
+
+func (h *httpServeMux) HandleAuth(pattern string, handler http.Handler, opts ...service.HandlerOption) {
+	options := service.NewHandlerOptions(opts...)
+	method := HTTPAuthMethod(h.scope, pattern)
+	if options.RequireAuth() {
+		subjects := options.Subjects
+		if len(subjects) == 0 {
+			// Any token subject if only roles required.
+			subjects = []permission.Subject{
+				permission.Subject_GUEST,
+				permission.Subject_WEB,
+				permission.Subject_PC,
+				permission.Subject_MOBILE,
+				permission.Subject_SERVER,
 			}
-		}()
-		//
-		ctx := service.ContextWithServiceMethod(r.Context(), &service.Method{
-			FullMethod:    r.URL.Path,
-			IsHttpGateway: true,
-		})
-		handler(w, r.WithContext(sctx.WithServerContext(ctx, h.component)))
+		}
+		auth.Implementor().RegisterServiceSubjects(h.component,
+			map[string][]permission.Subject{method: subjects},
+			map[string][]string{method: options.Roles})
+	}
+	h.serveMux.Handle(pattern, &handlerWrapper{
+		component:  h.component,
+		pattern:    pattern,
+		method:     method,
+		auth:       options.RequireAuth(),
+		gatewayMux: h.gatewayMux,
+		handler:    handler,
 	})
 }
+
+func (h *httpServeMux) HandleFuncAuth(pattern string, handler func(http.ResponseWriter, *http.Request), opts ...service.HandlerOption) {
+	h.HandleAuth(pattern, http.HandlerFunc(handler), opts...)
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/appootb/substratum/v2/auth"
+	"github.com/appootb/substratum/v2/gateway"
+	"github.com/appootb/substratum/v2/plugin/logger"
+	"github.com/appootb/substratum/v2/proto/go/common"
+	"github.com/appootb/substratum/v2/proto/go/permission"
+	"github.com/appootb/substratum/v2/proto/go/secret"
+	"github.com/appootb/substratum/v2/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type tokenParserFunc func(md *common.Metadata) (*secret.Info, error)
+
+func (fn tokenParserFunc) Parse(md *common.Metadata) (*secret.Info, error) {
+	return fn(md)
+}
+
+func init() {
+	logger.Init()
+	parser := tokenParserFunc(func(md *common.Metadata) (*secret.Info, error) {
+		switch md.GetToken() {
+		case "mobile":
+			return &secret.Info{Account: 1001, Subject: permission.Subject_MOBILE, Roles: []string{"user"}}, nil
+		case "server":
+			return &secret.Info{Account: 1002, Subject: permission.Subject_SERVER}, nil
+		}
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	})
+	auth.RegisterImplementor(auth.NewAlgorithmAuth(parser, parser))
+}
+
+func newTestHTTPMux(scope permission.VisibleScope) *httpServeMux {
+	return &httpServeMux{
+		component:  "test",
+		scope:      scope,
+		serveMux:   http.NewServeMux(),
+		gatewayMux: gateway.New(gateway.DefaultOptions),
+	}
+}
+
+func serveHTTP(h *httpServeMux, path, token string, header ...string) int {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		platform := common.Platform_PLATFORM_IOS
+		if token == "server" {
+			platform = common.Platform_PLATFORM_SERVER
+		}
+		r.Header.Set(gateway.MetadataHeaderPrefix+"Token", token)
+		r.Header.Set(gateway.MetadataHeaderPrefix+"Plf", strconv.Itoa(int(platform)))
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.serveMux.ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestHTTPServeMux_Auth(t *testing.T) {
+	h := newTestHTTPMux(permission.VisibleScope_CLIENT)
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		if service.AccountSecretFromContext(r.Context()).GetAccount() != 1001 {
+			w.WriteHeader(http.StatusTeapot)
+		}
+	}
+	h.HandleFuncAuth("/user", ok, service.WithRoles("user"))
+	h.HandleFuncAuth("/admin", ok, service.WithRoles("admin"))
+	h.HandleFunc("/plain", func(http.ResponseWriter, *http.Request) {})
+
+	cases := []struct {
+		path  string
+		token string
+		code  int
+	}{
+		{"/user", "", http.StatusUnauthorized},
+		{"/user", "mobile", http.StatusOK},
+		{"/admin", "mobile", http.StatusForbidden},
+		{"/plain", "", http.StatusOK},
+	}
+	for _, c := range cases {
+		if code := serveHTTP(h, c.path, c.token); code != c.code {
+			t.Fatal(c.path, c.token, "unexpected status", code)
+		}
+	}
+	// Plain handlers are served even if failed to annotate the incoming metadata.
+	if code := serveHTTP(h, "/plain", "", "Grpc-Timeout", "invalid"); code != http.StatusOK {
+		t.Fatal("plain handler rejected", code)
+	}
+	if code := serveHTTP(h, "/user", "mobile", "Grpc-Timeout", "invalid"); code == http.StatusOK {
+		t.Fatal("invalid metadata accepted")
+	}
+}
+
+func TestHTTPServeMux_ScopeAuth(t *testing.T) {
+	client := newTestHTTPMux(permission.VisibleScope_CLIENT)
+	inner := newTestHTTPMux(permission.VisibleScope_SERVER)
+	noop := func(http.ResponseWriter, *http.Request) {}
+	client.HandleFuncAuth("/scope", noop, service.WithSubjects(permission.Subject_MOBILE))
+	inner.HandleFuncAuth("/scope", noop, service.WithSubjects(permission.Subject_SERVER))
+
+	if code := serveHTTP(client, "/scope", "mobile"); code != http.StatusOK {
+		t.Fatal("client handler overridden by the server one", code)
+	}
+	if code := serveHTTP(inner, "/scope", "mobile"); code != http.StatusForbidden {
+		t.Fatal("server handler overridden by the client one", code)
+	}
+	if code := serveHTTP(inner, "/scope", "server"); code != http.StatusOK {
+		t.Fatal("server handler denied", code)
+	}
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"crypto/tls"
+	"net"
+	"os"
+
+	"github.com/appootb/substratum/v2/proto/go/permission"
+)
+
+// EnvAdvertiseHost overrides the host registered with discovery if WithAdvertiseAddr is not set.
+var EnvAdvertiseHost = os.Getenv("ADVERTISE_HOST")
+
+type Option func(*Options)
+
+type Options struct {
+	Scope           permission.VisibleScope
+	TLSConfig       *tls.Config
+	SinglePort      bool
+	RPCListener     net.Listener
+	GatewayListener net.Listener
+	AdvertiseHost   string
+	AdvertisePort   uint16
+}
+
+func NewOptions(opts ...Option) *Options {
+	options := &Options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// WithScope sets the visible scope of the mux, which namespaces the authentication of the http handlers.
+func WithScope(scope permission.VisibleScope) Option {
+	return func(options *Options) {
+		options.Scope = scope
+	}
+}
+
+// WithTLSConfig serves both the gRPC and gateway listeners over TLS,
+// set ClientCAs and ClientAuth of the config to enable mTLS.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(options *Options) {
+		options.TLSConfig = cfg
+	}
+}
+
+// WithSinglePort serves both gRPC and gateway requests on the rpc port,
+// the gateway port is ignored.
+func WithSinglePort() Option {
+	return func(options *Options) {
+		options.SinglePort = true
+	}
+}
+
+// WithRPCListener serves gRPC requests on the pre-opened listener instead of the rpc port,
+// e.g. unix domain sockets, systemd socket activation, or port 0 for tests.
+func WithRPCListener(lis net.Listener) Option {
+	return func(options *Options) {
+		options.RPCListener = lis
+	}
+}
+
+// WithGatewayListener serves gateway requests on the pre-opened listener instead of the gateway port.
+func WithGatewayListener(lis net.Listener) Option {
+	return func(options *Options) {
+		options.GatewayListener = lis
+	}
+}
+
+// WithAdvertiseAddr sets the address registered with discovery,
+// an empty host or zero port falls back to the bound one, e.g. for NAT or port mapping.
+func WithAdvertiseAddr(host string, port uint16) Option {
+	return func(options *Options) {
+		options.AdvertiseHost = host
+		options.AdvertisePort = port
+	}
+}
@@ -1,71 +1,244 @@
 package server
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 
+	"github.com/appootb/substratum/v2/auth"
 	"github.com/appootb/substratum/v2/gateway"
 	"github.com/appootb/substratum/v2/logger"
+	"github.com/appootb/substratum/v2/proto/go/permission"
 	"github.com/appootb/substratum/v2/rpc"
 	"github.com/appootb/substratum/v2/service"
 	"github.com/appootb/substratum/v2/util/iphelper"
 	prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 type ServeMux struct {
+	scope           permission.VisibleScope
 	metrics         bool
+	singlePort      bool
 	connAddr        string
 	rpcListener     net.Listener
 	gatewayListener net.Listener
 
 	rpcSrv     *grpc.Server
+	healthSrv  *health.Server
+	httpSrv    *http.Server
 	httpMux    *http.ServeMux
 	gatewayMux *runtime.ServeMux
+
+	// In-flight gRPC requests served by the http server in single port mode,
+	// new requests are rejected after stopping.
+	mu          sync.Mutex
+	stopping    bool
+	rpcRequests sync.WaitGroup
 }
 
-func NewServeMux(rpcPort, gatewayPort uint16, metrics bool) (*ServeMux, error) {
+func NewServeMux(rpcPort, gatewayPort uint16, metrics bool, opts ...Option) (*ServeMux, error) {
 	var err error
+	options := NewOptions(opts...)
+	rpcOpts := []rpc.ServerOption{
+		rpc.WithDefaultKeepaliveOption(),
+		rpc.WithDefaultUnaryInterceptors(),
+		rpc.WithDefaultStreamInterceptors(),
+	}
+	if options.TLSConfig != nil && !options.SinglePort {
+		rpcOpts = append(rpcOpts, rpc.WithServerOption(grpc.Creds(credentials.NewTLS(options.TLSConfig))))
+	}
 	m := &ServeMux{
-		rpcSrv: rpc.New(
-			rpc.NewOptions(
-				rpc.WithDefaultKeepaliveOption(),
-				rpc.WithDefaultUnaryInterceptors(),
-				rpc.WithDefaultStreamInterceptors(),
-			),
-		),
+		rpcSrv:     rpc.New(rpc.NewOptions(rpcOpts...)),
+		scope:      options.Scope,
 		metrics:    metrics,
+		singlePort: options.SinglePort,
+		healthSrv:  health.NewServer(),
 		httpMux:    http.NewServeMux(),
 		gatewayMux: gateway.New(gateway.DefaultOptions),
 	}
-	m.connAddr = fmt.Sprintf("%s:%d", iphelper.LocalIP(), rpcPort)
-	m.rpcListener, err = net.Listen("tcp", fmt.Sprintf(":%d", rpcPort))
-	if err != nil {
-		return nil, err
+	m.rpcListener = options.RPCListener
+	if m.rpcListener == nil {
+		m.rpcListener, err = net.Listen("tcp", fmt.Sprintf(":%d", rpcPort))
+		if err != nil {
+			return nil, err
+		}
 	}
-	m.gatewayListener, err = net.Listen("tcp", fmt.Sprintf(":%d", gatewayPort))
-	if err != nil {
-		return nil, err
+	m.connAddr = connAddr(m.rpcListener.Addr(), options)
+	m.httpSrv = &http.Server{
+		Handler: m.httpMux,
+	}
+	if m.singlePort {
+		// gRPC and gateway share the rpc listener.
+		m.gatewayListener = m.rpcListener
+		if err = m.configureSinglePort(options.TLSConfig); err != nil {
+			_ = m.rpcListener.Close()
+			return nil, err
+		}
+	} else {
+		m.gatewayListener = options.GatewayListener
+		if m.gatewayListener == nil {
+			m.gatewayListener, err = net.Listen("tcp", fmt.Sprintf(":%d", gatewayPort))
+			if err != nil {
+				_ = m.rpcListener.Close()
+				return nil, err
+			}
+		}
+		if options.TLSConfig != nil {
+			m.gatewayListener = tls.NewListener(m.gatewayListener, options.TLSConfig)
+		}
 	}
 	m.httpMux.Handle("/", m.gatewayMux)
+	m.httpMux.HandleFunc("/healthz", m.liveness)
+	m.httpMux.HandleFunc("/readyz", m.readiness)
 	if metrics {
 		m.httpMux.Handle("/metrics", promhttp.Handler())
 	}
+	// Not ready before the node registered.
+	m.healthSrv.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(m.rpcSrv, m.healthSrv)
+	auth.Implementor().RegisterServiceSubjects("", map[string][]permission.Subject{
+		fmt.Sprintf("/%s/Check", healthpb.Health_ServiceDesc.ServiceName): {permission.Subject_NONE},
+		fmt.Sprintf("/%s/Watch", healthpb.Health_ServiceDesc.ServiceName): {permission.Subject_NONE},
+	}, nil)
 	return m, nil
 }
 
+// configureSinglePort dispatches gRPC requests to the rpc server and the others to the http mux,
+// HTTP/2 is negotiated by ALPN over TLS, or served as h2c over plaintext connections.
+func (m *ServeMux) configureSinglePort(cfg *tls.Config) error {
+	h2Srv := &http2.Server{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			if !m.addRPCRequest() {
+				http.Error(w, "server stopping", http.StatusServiceUnavailable)
+				return
+			}
+			defer m.rpcRequests.Done()
+			m.rpcSrv.ServeHTTP(w, r)
+			return
+		}
+		m.httpMux.ServeHTTP(w, r)
+	})
+	if cfg == nil {
+		m.httpSrv.Handler = h2c.NewHandler(handler, h2Srv)
+	} else {
+		cfg = cfg.Clone()
+		if !containsProto(cfg.NextProtos, http2.NextProtoTLS) {
+			cfg.NextProtos = append([]string{http2.NextProtoTLS}, cfg.NextProtos...)
+		}
+		m.httpSrv.Handler = handler
+		m.gatewayListener = tls.NewListener(m.rpcListener, cfg)
+	}
+	// Register the http2 server for graceful shutdown and TLS protocol negotiation.
+	return http2.ConfigureServer(m.httpSrv, h2Srv)
+}
+
+// addRPCRequest tracks a gRPC request served in single port mode, returns false if stopping.
+func (m *ServeMux) addRPCRequest() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopping {
+		return false
+	}
+	m.rpcRequests.Add(1)
+	return true
+}
+
+// connAddr returns the address for dialing the bound listener, overridden by the advertised host and port.
+// The local IP is used if the listener is bound to an unspecified address.
+func connAddr(addr net.Addr, options *Options) string {
+	host, port := options.AdvertiseHost, int(options.AdvertisePort)
+	if host == "" {
+		host = EnvAdvertiseHost
+	}
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		if host == "" {
+			if a.IP == nil || a.IP.IsUnspecified() {
+				host = iphelper.LocalIP()
+			} else {
+				host = a.IP.String()
+			}
+		}
+		if port == 0 {
+			port = a.Port
+		}
+	default:
+		if host == "" || port == 0 {
+			if a, ok := addr.(*net.UnixAddr); ok {
+				return "unix:" + a.Name
+			}
+			return addr.String()
+		}
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+func containsProto(protos []string, proto string) bool {
+	for _, p := range protos {
+		if p == proto {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *ServeMux) RPCServer() *grpc.Server {
 	return m.rpcSrv
 }
 
+// SetServingStatus updates the health status of the service,
+// an empty service name indicates the overall status of the mux.
+func (m *ServeMux) SetServingStatus(service string, serving bool) {
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if serving {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+	m.healthSrv.SetServingStatus(service, status)
+}
+
+// SetNotServing sets all the services to NOT_SERVING and ignores the status updates afterwards.
+func (m *ServeMux) SetNotServing() {
+	m.healthSrv.Shutdown()
+}
+
+func (m *ServeMux) liveness(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
+
+func (m *ServeMux) readiness(w http.ResponseWriter, r *http.Request) {
+	resp, err := m.healthSrv.Check(r.Context(), &healthpb.HealthCheckRequest{
+		Service: r.URL.Query().Get("service"),
+	})
+	if err != nil || resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(healthpb.HealthCheckResponse_NOT_SERVING.String()))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(resp.GetStatus().String()))
+}
+
 func (m *ServeMux) HTTPMux(comp string) service.HttpHandler {
 	return &httpServeMux{
-		component: comp,
-		serveMux:  m.httpMux,
+		component:  comp,
+		scope:      m.scope,
+		serveMux:   m.httpMux,
+		gatewayMux: m.gatewayMux,
 	}
 }
 
@@ -79,22 +252,25 @@ func (m *ServeMux) Serve() {
 	}
 	//
 	wg := sync.WaitGroup{}
-	wg.Add(2)
-	go func() {
-		wg.Done()
-		err := m.rpcSrv.Serve(m.rpcListener)
-		if err != nil {
-			logger.Error("rpc_server", logger.Content{
-				"server": "gRPC",
-				"addr":   m.rpcListener.Addr(),
-				"err":    err.Error(),
-			})
-		}
-	}()
+	if !m.singlePort {
+		wg.Add(1)
+		go func() {
+			wg.Done()
+			err := m.rpcSrv.Serve(m.rpcListener)
+			if err != nil {
+				logger.Error("rpc_server", logger.Content{
+					"server": "gRPC",
+					"addr":   m.rpcListener.Addr(),
+					"err":    err.Error(),
+				})
+			}
+		}()
+	}
+	wg.Add(1)
 	go func() {
 		wg.Done()
-		err := http.Serve(m.gatewayListener, m.httpMux)
-		if err != nil {
+		err := m.httpSrv.Serve(m.gatewayListener)
+		if err != nil && err != http.ErrServerClosed {
 			logger.Error("gateway_server", logger.Content{
 				"server": "gateway",
 				"addr":   m.gatewayListener.Addr(),
@@ -106,6 +282,51 @@ func (m *ServeMux) Serve() {
 	wg.Wait()
 }
 
+// ConnAddr returns the advertised address of the rpc listener for dialing.
 func (m *ServeMux) ConnAddr() string {
 	return m.connAddr
 }
+
+// GatewayAddr returns the address of the bound gateway listener,
+// which is the same as the rpc listener in single port mode.
+func (m *ServeMux) GatewayAddr() net.Addr {
+	return m.gatewayListener.Addr()
+}
+
+// Shutdown stops accepting new connections and waits for the in-flight requests to complete.
+// The gRPC server is stopped forcibly if the context expires before draining.
+func (m *ServeMux) Shutdown(ctx context.Context) error {
+	// New gRPC requests are rejected, including the ones on the hijacked h2c connections.
+	m.mu.Lock()
+	m.stopping = true
+	m.mu.Unlock()
+
+	errChan := make(chan error, 1)
+	httpStopped := make(chan struct{})
+	go func() {
+		errChan <- m.httpSrv.Shutdown(ctx)
+		close(httpStopped)
+	}()
+	stopped := make(chan struct{})
+	go func() {
+		if m.singlePort {
+			// The handler transport of the gRPC server does not support draining,
+			// wait for the in-flight requests after the connections are going away.
+			<-httpStopped
+			m.rpcRequests.Wait()
+		} else {
+			m.rpcSrv.GracefulStop()
+		}
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		if m.singlePort {
+			m.rpcSrv.Stop()
+		}
+	case <-ctx.Done():
+		m.rpcSrv.Stop()
+	}
+	return <-errChan
+}
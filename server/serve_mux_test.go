@@ -0,0 +1,255 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/appootb/substratum/v2/util/iphelper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func newTestServeMux(t *testing.T, opts ...Option) *ServeMux {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewServeMux(0, 0, false, append([]Option{WithRPCListener(lis)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func httpGet(t *testing.T, url string) (int, string) {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestServeMux_SinglePort(t *testing.T) {
+	m := newTestServeMux(t, WithSinglePort())
+	defer m.Shutdown(context.Background())
+	m.Serve()
+	if m.GatewayAddr() != m.rpcListener.Addr() {
+		t.Fatal("gateway not served on the rpc listener", m.GatewayAddr())
+	}
+	base := "http://" + m.ConnAddr()
+
+	for _, c := range []struct {
+		serving bool
+		path    string
+		code    int
+		body    string
+	}{
+		{false, "/healthz", http.StatusOK, "ok"},
+		{false, "/readyz", http.StatusServiceUnavailable, "NOT_SERVING"},
+		{true, "/readyz", http.StatusOK, "SERVING"},
+	} {
+		m.SetServingStatus("", c.serving)
+		if code, body := httpGet(t, base+c.path); code != c.code || body != c.body {
+			t.Fatal(c.path, "unexpected response", code, body)
+		}
+	}
+
+	cc, err := grpc.Dial(m.ConnAddr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	resp, err := healthpb.NewHealthClient(cc).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Fatal("unexpected health status", resp.GetStatus())
+	}
+}
+
+// drainServiceDesc has a method blocked until released, the interceptors are skipped.
+func drainServiceDesc(started, release chan struct{}) *grpc.ServiceDesc {
+	return &grpc.ServiceDesc{
+		ServiceName: "substratum.test.Drain",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{
+			{
+				MethodName: "Wait",
+				Handler: func(_ interface{}, _ context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+					if err := dec(&emptypb.Empty{}); err != nil {
+						return nil, err
+					}
+					close(started)
+					<-release
+					return &emptypb.Empty{}, nil
+				},
+			},
+		},
+	}
+}
+
+func TestServeMux_SinglePortShutdown(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	m := newTestServeMux(t, WithSinglePort())
+	m.RPCServer().RegisterService(drainServiceDesc(started, release), struct{}{})
+	m.Serve()
+
+	cc, err := grpc.Dial(m.ConnAddr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	called := make(chan error, 1)
+	go func() {
+		called <- cc.Invoke(context.Background(), "/substratum.test.Drain/Wait", &emptypb.Empty{}, &emptypb.Empty{})
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- m.Shutdown(ctx)
+	}()
+	select {
+	case err = <-stopped:
+		t.Fatal("stopped before draining", err)
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	// New gRPC requests are rejected while draining.
+	r := httptest.NewRequest(http.MethodPost, "/substratum.test.Drain/Wait", nil)
+	r.ProtoMajor = 2
+	r.Header.Set("Content-Type", "application/grpc")
+	w := httptest.NewRecorder()
+	m.httpSrv.Handler.ServeHTTP(w, r)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatal("request accepted while stopping", w.Code)
+	}
+
+	close(release)
+	if err = <-called; err != nil {
+		t.Fatal("in-flight request not drained", err)
+	}
+	if err = <-stopped; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServeMux_Health(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := newTestServeMux(t, WithGatewayListener(lis))
+	defer m.Shutdown(context.Background())
+	m.Serve()
+	base := "http://" + m.GatewayAddr().String()
+
+	cc, err := grpc.Dial(m.ConnAddr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	client := healthpb.NewHealthClient(cc)
+
+	for _, c := range []struct {
+		name    string
+		update  func()
+		service string
+		code    int
+		status  healthpb.HealthCheckResponse_ServingStatus
+	}{
+		{"not registered", func() {}, "", http.StatusServiceUnavailable, healthpb.HealthCheckResponse_NOT_SERVING},
+		{"registered", func() { m.SetServingStatus("", true) }, "", http.StatusOK, healthpb.HealthCheckResponse_SERVING},
+		{"service serving", func() { m.SetServingStatus("svc", true) }, "svc", http.StatusOK, healthpb.HealthCheckResponse_SERVING},
+		{"service unhealthy", func() { m.SetServingStatus("svc", false) }, "svc", http.StatusServiceUnavailable, healthpb.HealthCheckResponse_NOT_SERVING},
+		{"shutting down", m.SetNotServing, "", http.StatusServiceUnavailable, healthpb.HealthCheckResponse_NOT_SERVING},
+		{"updated after shutdown", func() { m.SetServingStatus("", true) }, "", http.StatusServiceUnavailable, healthpb.HealthCheckResponse_NOT_SERVING},
+	} {
+		c.update()
+		if code, _ := httpGet(t, base+"/readyz?service="+c.service); code != c.code {
+			t.Fatal(c.name, "unexpected readiness", code)
+		}
+		if code, body := httpGet(t, base+"/healthz"); code != http.StatusOK || body != "ok" {
+			t.Fatal(c.name, "unexpected liveness", code, body)
+		}
+		resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: c.service})
+		if err != nil {
+			t.Fatal(c.name, err)
+		}
+		if resp.GetStatus() != c.status {
+			t.Fatal(c.name, "unexpected health status", resp.GetStatus())
+		}
+	}
+}
+
+func TestConnAddr(t *testing.T) {
+	defer func(host string) {
+		EnvAdvertiseHost = host
+	}(EnvAdvertiseHost)
+
+	loopback := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080}
+	unspecified := &net.TCPAddr{IP: net.IPv4zero, Port: 8080}
+	unix := &net.UnixAddr{Name: "/tmp/substratum.sock", Net: "unix"}
+
+	for _, c := range []struct {
+		name     string
+		addr     net.Addr
+		env      string
+		host     string
+		port     uint16
+		expected string
+	}{
+		{"bound", loopback, "", "", 0, "127.0.0.1:8080"},
+		{"unspecified", unspecified, "", "", 0, net.JoinHostPort(iphelper.LocalIP(), "8080")},
+		{"env host", unspecified, "10.0.0.1", "", 0, "10.0.0.1:8080"},
+		{"advertised host", loopback, "10.0.0.1", "10.0.0.2", 0, "10.0.0.2:8080"},
+		{"advertised port", loopback, "", "", 9090, "127.0.0.1:9090"},
+		{"unix", unix, "", "", 0, "unix:/tmp/substratum.sock"},
+		{"unix without port", unix, "10.0.0.1", "", 0, "unix:/tmp/substratum.sock"},
+		{"unix advertised", unix, "", "10.0.0.2", 9090, "10.0.0.2:9090"},
+	} {
+		EnvAdvertiseHost = c.env
+		options := NewOptions(WithAdvertiseAddr(c.host, c.port))
+		if addr := connAddr(c.addr, options); addr != c.expected {
+			t.Fatalf("%s: conn addr %s, expected %s", c.name, addr, c.expected)
+		}
+	}
+}
+
+func TestServeMux_UnixListener(t *testing.T) {
+	lis, err := net.Listen("unix", filepath.Join(t.TempDir(), "rpc.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewServeMux(0, 0, false, WithRPCListener(lis), WithSinglePort())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Shutdown(context.Background())
+	m.Serve()
+	if m.ConnAddr() != "unix:"+lis.Addr().String() {
+		t.Fatal("unexpected conn addr", m.ConnAddr())
+	}
+
+	cc, err := grpc.Dial(m.ConnAddr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	if _, err = healthpb.NewHealthClient(cc).Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+}
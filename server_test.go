@@ -0,0 +1,167 @@
+package substratum
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/appootb/substratum/v2/configure"
+	"github.com/appootb/substratum/v2/proto/go/permission"
+	"github.com/appootb/substratum/v2/queue"
+	"github.com/appootb/substratum/v2/server"
+	"github.com/appootb/substratum/v2/service"
+	"github.com/appootb/substratum/v2/storage"
+	"github.com/appootb/substratum/v2/task"
+)
+
+// baseComponent implements neither Starter nor Stopper.
+type baseComponent struct {
+	name string
+}
+
+func (c *baseComponent) Name() string                                                     { return c.name }
+func (c *baseComponent) Init(configure.Configure) error                                   { return nil }
+func (c *baseComponent) InitStorage(storage.Storage) error                                { return nil }
+func (c *baseComponent) RegisterHandler(_, _ service.HttpHandler) error                   { return nil }
+func (c *baseComponent) RegisterService(service.Authenticator, service.Implementor) error { return nil }
+func (c *baseComponent) RunQueueWorker(queue.Queue) error                                 { return nil }
+func (c *baseComponent) ScheduleCronTask(task.Task) error                                 { return nil }
+
+// fakeComponent records the lifecycle hooks invoked.
+type fakeComponent struct {
+	baseComponent
+	events   *[]string
+	startErr error
+	stopErr  error
+}
+
+func (c *fakeComponent) Start(ctx context.Context) error {
+	// Started with the server context of the component.
+	*c.events = append(*c.events, "start "+service.ComponentNameFromContext(ctx))
+	return c.startErr
+}
+
+func (c *fakeComponent) Stop(context.Context) error {
+	*c.events = append(*c.events, "stop "+c.name)
+	return c.stopErr
+}
+
+// healthComponent reports the health status.
+type healthComponent struct {
+	baseComponent
+	err error
+}
+
+func (c *healthComponent) Health(context.Context) error {
+	return c.err
+}
+
+func newTestServer(comps ...Component) *Server {
+	return &Server{
+		ctx:             context.Background(),
+		keepAliveTTL:    time.Second,
+		healthInterval:  time.Second,
+		shutdownTimeout: time.Second,
+		quit:            make(chan struct{}),
+		components:      comps,
+		rpcServices:     make(map[string][]string),
+		serveMuxers:     make(map[permission.VisibleScope]*server.ServeMux),
+	}
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	errStop := errors.New("stop failed")
+
+	for _, c := range []struct {
+		name    string
+		started int
+		events  []string
+	}{
+		{"all started", 4, []string{"stop d", "stop b", "stop a"}},
+		{"partially started", 2, []string{"stop b", "stop a"}},
+		{"none started", 0, nil},
+	} {
+		var events []string
+		srv := newTestServer(
+			&fakeComponent{baseComponent: baseComponent{"a"}, events: &events},
+			&fakeComponent{baseComponent: baseComponent{"b"}, events: &events, stopErr: errStop},
+			&baseComponent{"c"},
+			&fakeComponent{baseComponent: baseComponent{"d"}, events: &events},
+		)
+		srv.started = c.started
+
+		err := srv.Shutdown(context.Background())
+		if !reflect.DeepEqual(events, c.events) {
+			t.Fatalf("%s: stopped %v, expected %v", c.name, events, c.events)
+		}
+		if c.started > 1 && err != errStop {
+			t.Fatalf("%s: expected %v, got %v", c.name, errStop, err)
+		}
+		select {
+		case <-srv.quit:
+		default:
+			t.Fatal(c.name, "quit not closed")
+		}
+		// Shut down only once.
+		if again := srv.Shutdown(context.Background()); again != err {
+			t.Fatalf("%s: shutdown again returned %v, expected %v", c.name, again, err)
+		}
+		if !reflect.DeepEqual(events, c.events) {
+			t.Fatalf("%s: stopped again %v", c.name, events)
+		}
+	}
+}
+
+func TestServer_ServeAbort(t *testing.T) {
+	errStart := errors.New("start failed")
+
+	var events []string
+	srv := newTestServer(
+		&fakeComponent{baseComponent: baseComponent{"a"}, events: &events},
+		&baseComponent{"b"},
+		&fakeComponent{baseComponent: baseComponent{"c"}, events: &events, startErr: errStart},
+		&fakeComponent{baseComponent: baseComponent{"d"}, events: &events},
+	)
+	if err := srv.Serve(); err != errStart {
+		t.Fatalf("expected %v, got %v", errStart, err)
+	}
+	expected := []string{"start a", "start c", "stop a"}
+	if !reflect.DeepEqual(events, expected) {
+		t.Fatalf("events %v, expected %v", events, expected)
+	}
+	if srv.started != 2 {
+		t.Fatalf("started %d components, expected 2", srv.started)
+	}
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatal("shutdown after abort", err)
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Fatalf("stopped again %v", events)
+	}
+}
+
+func TestServer_Health(t *testing.T) {
+	errUnhealthy := errors.New("unhealthy")
+
+	for _, c := range []struct {
+		name       string
+		components []Component
+		health     map[string]error
+	}{
+		{"no components", nil, map[string]error{}},
+		{"no checker", []Component{&baseComponent{"a"}}, map[string]error{"a": nil}},
+		{"healthy", []Component{&healthComponent{baseComponent{"a"}, nil}}, map[string]error{"a": nil}},
+		{"unhealthy", []Component{
+			&baseComponent{"a"},
+			&healthComponent{baseComponent{"b"}, errUnhealthy},
+			&healthComponent{baseComponent{"c"}, nil},
+		}, map[string]error{"a": nil, "b": errUnhealthy, "c": nil}},
+	} {
+		health := newTestServer(c.components...).Health(context.Background())
+		if !reflect.DeepEqual(health, c.health) {
+			t.Fatalf("%s: health %v, expected %v", c.name, health, c.health)
+		}
+	}
+}
@@ -21,6 +21,16 @@ type HttpHandler interface {
 	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request))
 }
 
+// HttpAuthHandler is implemented by the http handlers authenticating requests, detected by type assertion.
+type HttpAuthHandler interface {
+	// HandleAuth registers the handler for the given pattern,
+	// the request is authenticated if any of the subjects or roles is required by the options.
+	HandleAuth(pattern string, handler http.Handler, opts ...HandlerOption)
+
+	// HandleFuncAuth registers the handler function for the given pattern, authenticated the same as HandleAuth.
+	HandleFuncAuth(pattern string, handler func(http.ResponseWriter, *http.Request), opts ...HandlerOption)
+}
+
 // Implementor interface.
 type Implementor interface {
 	// Context returns the service context.
@@ -162,6 +172,10 @@ func ComponentNameFromContext(ctx context.Context) string {
 	return ""
 }
 
+func ContextWithAccountSecret(ctx context.Context, secretInfo *secret.Info) context.Context {
+	return context.WithValue(ctx, secretKey{}, secretInfo)
+}
+
 func AccountSecretFromContext(ctx context.Context) *secret.Info {
 	if secretInfo := ctx.Value(secretKey{}); secretInfo != nil {
 		return secretInfo.(*secret.Info)
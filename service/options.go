@@ -0,0 +1,37 @@
+package service
+
+import "github.com/appootb/substratum/v2/proto/go/permission"
+
+type HandlerOption func(*HandlerOptions)
+
+type HandlerOptions struct {
+	Subjects []permission.Subject
+	Roles    []string
+}
+
+func NewHandlerOptions(opts ...HandlerOption) *HandlerOptions {
+	options := &HandlerOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// RequireAuth returns true if the handler requires authentication.
+func (o *HandlerOptions) RequireAuth() bool {
+	return len(o.Subjects) > 0 || len(o.Roles) > 0
+}
+
+// WithSubjects sets the required token subjects of the handler.
+func WithSubjects(subjects ...permission.Subject) HandlerOption {
+	return func(options *HandlerOptions) {
+		options.Subjects = append(options.Subjects, subjects...)
+	}
+}
+
+// WithRoles sets the required roles of the handler.
+func WithRoles(roles ...string) HandlerOption {
+	return func(options *HandlerOptions) {
+		options.Roles = append(options.Roles, roles...)
+	}
+}
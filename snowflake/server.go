@@ -0,0 +1,45 @@
+package snowflake
+
+import (
+	"context"
+
+	sf "github.com/appootb/substratum/v2/util/snowflake"
+	"google.golang.org/grpc"
+)
+
+type snowflakeKey struct{}
+
+// UnaryServerInterceptor returns a new unary server interceptor for snowflake generator.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(ContextWithSnowflake(ctx), req)
+	}
+}
+
+// StreamServerInterceptor returns a new streaming server interceptor for snowflake generator.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		wrapper := &ctxWrapper{stream}
+		return handler(srv, wrapper)
+	}
+}
+
+type ctxWrapper struct {
+	grpc.ServerStream
+}
+
+func (s *ctxWrapper) Context() context.Context {
+	ctx := s.ServerStream.Context()
+	return ContextWithSnowflake(ctx)
+}
+
+func ContextWithSnowflake(ctx context.Context) context.Context {
+	return context.WithValue(ctx, snowflakeKey{}, impl)
+}
+
+func ContextSnowflake(ctx context.Context, component string) *sf.Snowflake {
+	if gen := ctx.Value(snowflakeKey{}); gen != nil {
+		return gen.(Generator).Get(component)
+	}
+	return nil
+}
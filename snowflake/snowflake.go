@@ -0,0 +1,33 @@
+package snowflake
+
+import (
+	"time"
+
+	sf "github.com/appootb/substratum/v2/util/snowflake"
+)
+
+var (
+	impl Generator
+)
+
+// Implementor returns the snowflake generator service implementor.
+func Implementor() Generator {
+	return impl
+}
+
+// RegisterImplementor registers the snowflake generator service implementor.
+func RegisterImplementor(gen Generator) {
+	impl = gen
+}
+
+// Generator interface.
+type Generator interface {
+	// Allocate a partition ID for the component and keep it alive with the ttl.
+	// An empty component name allocates the node-level partition shared by the process.
+	Allocate(component string, ttl time.Duration) (*sf.Snowflake, error)
+	// Get returns the snowflake of the component,
+	// the node-level snowflake is returned if the component is not allocated.
+	Get(component string) *sf.Snowflake
+	// Release all the allocated partition IDs.
+	Release() error
+}
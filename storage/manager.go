@@ -18,3 +18,9 @@ type Manager interface {
 	New(component string)
 	Get(component string) Storage
 }
+
+// Closer is implemented by the managers holding connections, detected by type assertion.
+type Closer interface {
+	// Close the connections of all the components.
+	Close() error
+}
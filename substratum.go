@@ -1,7 +1,10 @@
 package substratum
 
 import (
+	"context"
+
 	"github.com/appootb/substratum/v2/proto/go/permission"
+	"github.com/appootb/substratum/v2/server"
 	"github.com/appootb/substratum/v2/service"
 )
 
@@ -9,11 +12,19 @@ type Service interface {
 	service.Implementor
 
 	// AddServeMux adds scoped ServeMux.
-	AddServeMux(permission.VisibleScope, uint16, uint16) error
+	AddServeMux(permission.VisibleScope, uint16, uint16, ...server.Option) error
 
 	// Register component.
 	Register(Component, ...string) error
 
 	// Serve start the mux server.
 	Serve(isolate ...bool) error
+
+	// Health reports the health status of the registered components,
+	// the value is nil if the component is healthy.
+	Health(ctx context.Context) map[string]error
+
+	// Shutdown deregisters the node, drains in-flight requests
+	// and stops the queue workers and cron tasks.
+	Shutdown(ctx context.Context) error
 }
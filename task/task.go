@@ -31,3 +31,9 @@ type Task interface {
 	// Supported spec, refer: https://github.com/robfig/cron/tree/v3.0.1
 	Schedule(spec string, exec Executor, opts ...Option) error
 }
+
+// Shutdowner is implemented by the tasks scheduling in background, detected by type assertion.
+type Shutdowner interface {
+	// Shutdown stops scheduling, waits for the running executions and releases the singleton lockers.
+	Shutdown(ctx context.Context) error
+}
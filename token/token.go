@@ -1,14 +1,61 @@
 package token
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+
 	"github.com/appootb/substratum/v2/proto/go/common"
 	"github.com/appootb/substratum/v2/proto/go/secret"
 )
 
+const (
+	// JWKSPath is the well-known path of the JSON Web Key Set published on the SERVER gateway.
+	JWKSPath = "/.well-known/jwks.json"
+	// APIKeyScheme is the prefix of the tokens signed by API keys,
+	// in the format of `HMAC-SHA256 <key_id>:<signature>`.
+	APIKeyScheme = "HMAC-SHA256 "
+)
+
 var (
 	impl Token
 )
 
+// AlgorithmNames are the JWA names of the signing algorithms,
+// used as the `alg` of the token headers and the published JSON Web Keys.
+var AlgorithmNames = map[secret.Algorithm]string{
+	secret.Algorithm_HMAC:  "HS512",
+	secret.Algorithm_RSA:   "RS512",
+	secret.Algorithm_PSS:   "PS512",
+	secret.Algorithm_ECDSA: "ES512",
+	secret.Algorithm_EdDSA: "EdDSA",
+}
+
+// ECDSAAlgorithmNames are the JWA names of the ECDSA signing algorithms by the curve,
+// AlgorithmNames has the name of the P-521 keys created by NewSecretKey.
+var ECDSAAlgorithmNames = map[string]string{
+	"P-256": "ES256",
+	"P-384": "ES384",
+	"P-521": "ES512",
+}
+
+// AlgorithmByName returns the signing algorithm of the JWA name, secret.Algorithm_None if not supported.
+func AlgorithmByName(name string) secret.Algorithm {
+	for alg, n := range AlgorithmNames {
+		if n == name {
+			return alg
+		}
+	}
+	for _, n := range ECDSAAlgorithmNames {
+		if n == name {
+			return secret.Algorithm_ECDSA
+		}
+	}
+	return secret.Algorithm_None
+}
+
 // Implementor return the token service implementor.
 func Implementor() Token {
 	return impl
@@ -32,3 +79,52 @@ type Token interface {
 	// ParseRaw parses a token string.
 	ParseRaw(token string) (*secret.Info, error)
 }
+
+// SessionGenerator is implemented by the token recording the client sessions with the request metadata,
+// detected by type assertion.
+type SessionGenerator interface {
+	// GenerateWithMetadata generates a new token and records the client session with the request metadata.
+	GenerateWithMetadata(s *secret.Info, md *common.Metadata) (string, error)
+}
+
+// PairGenerator is implemented by the token issuing rotating refresh tokens, detected by type assertion.
+type PairGenerator interface {
+	// GeneratePair generates a new access token and a long-lived opaque refresh token.
+	GeneratePair(s *secret.Info, md *common.Metadata, refreshTTL time.Duration) (*Pair, error)
+	// RefreshPair rotates the refresh token and generates a new access token,
+	// all the keys of the account are revoked if a rotated refresh token is reused.
+	RefreshPair(refreshToken string) (*Pair, error)
+}
+
+// Pair of the access token and refresh token.
+type Pair struct {
+	AccessToken      string
+	RefreshToken     string
+	AccessExpiredAt  time.Time
+	RefreshExpiredAt time.Time
+}
+
+// KeySet is implemented by the token publishing the public server keys for verification.
+type KeySet interface {
+	// JWKS returns the JSON Web Key Set of the asymmetric server keys.
+	JWKS() ([]byte, error)
+}
+
+// BodyDigest returns the digest of the request body for signing.
+func BodyDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeySignature returns the HMAC-SHA256 signature of the key ID, timestamp (in millisecond) and body digest.
+func APIKeySignature(keyID int64, key []byte, timestamp int64, bodyDigest string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strconv.FormatInt(keyID, 10) + "\n" + strconv.FormatInt(timestamp, 10) + "\n" + bodyDigest))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignAPIKey returns the token of a request signed by the API key,
+// the timestamp should be sent as metadata.KeyTimestamp.
+func SignAPIKey(keyID int64, key []byte, timestamp int64, bodyDigest string) string {
+	return APIKeyScheme + strconv.FormatInt(keyID, 10) + ":" + APIKeySignature(keyID, key, timestamp, bodyDigest)
+}
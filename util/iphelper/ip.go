@@ -2,18 +2,122 @@ package iphelper
 
 import (
 	"net"
+	"os"
+	"strings"
+	"sync"
 )
 
+// EnvPreferences is the comma separated interface names or CIDRs preferred by LocalIP.
+const EnvPreferences = "IP_PREFERENCES"
+
+var (
+	mu          sync.RWMutex
+	preferences []preference
+)
+
+func init() {
+	if env := os.Getenv(EnvPreferences); env != "" {
+		_ = SetPreferences(strings.Split(env, ",")...)
+	}
+}
+
+type preference struct {
+	iface string
+	cidr  *net.IPNet
+}
+
+func (p preference) match(iface net.Interface, ip net.IP) bool {
+	if p.cidr != nil {
+		return p.cidr.Contains(ip)
+	}
+	return p.iface == iface.Name
+}
+
+// SetPreferences sets the interface names or CIDRs in order of preference,
+// LocalIP returns the first address matched, or falls back to the first non-loopback address.
+func SetPreferences(prefs ...string) error {
+	parsed := make([]preference, 0, len(prefs))
+	for _, p := range prefs {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if !strings.Contains(p, "/") {
+			parsed = append(parsed, preference{iface: p})
+			continue
+		}
+		_, cidr, err := net.ParseCIDR(p)
+		if err != nil {
+			return err
+		}
+		parsed = append(parsed, preference{cidr: cidr})
+	}
+	mu.Lock()
+	preferences = parsed
+	mu.Unlock()
+	return nil
+}
+
 func LocalIP() string {
-	if ipv4, err := localIP(true); err == nil {
+	if ip, err := preferredIP(); err == nil && ip != nil {
+		return ip.String()
+	}
+	if ipv4, err := localIP(true); err == nil && ipv4 != nil {
 		return ipv4.String()
 	}
-	if ipv6, err := localIP(false); err == nil {
+	if ipv6, err := localIP(false); err == nil && ipv6 != nil {
 		return ipv6.String()
 	}
 	return "127.0.0.1"
 }
 
+func preferredIP() (net.IP, error) {
+	mu.RLock()
+	prefs := preferences
+	mu.RUnlock()
+	if len(prefs) == 0 {
+		return nil, nil
+	}
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	for _, pref := range prefs {
+		for _, iface := range ifaces {
+			if iface.Flags&net.FlagUp == 0 {
+				continue
+			}
+			ips, err := interfaceIPs(iface)
+			if err != nil {
+				return nil, err
+			}
+			for _, ip := range ips {
+				if pref.match(iface, ip) {
+					return ip, nil
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+func interfaceIPs(iface net.Interface) ([]net.IP, error) {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		switch v := addr.(type) {
+		case *net.IPNet:
+			ips = append(ips, v.IP)
+		case *net.IPAddr:
+			ips = append(ips, v.IP)
+		}
+	}
+	return ips, nil
+}
+
 func localIP(v4 bool) (net.IP, error) {
 	ifaces, err := net.Interfaces()
 	if err != nil {
@@ -25,18 +129,11 @@ func localIP(v4 bool) (net.IP, error) {
 			iface.Flags&net.FlagPointToPoint != 0 { // Point to point
 			continue
 		}
-		addrs, err := iface.Addrs()
+		ips, err := interfaceIPs(iface)
 		if err != nil {
 			return nil, err
 		}
-		for _, addr := range addrs {
-			var ip net.IP
-			switch v := addr.(type) {
-			case *net.IPNet:
-				ip = v.IP
-			case *net.IPAddr:
-				ip = v.IP
-			}
+		for _, ip := range ips {
 			if ip == nil || ip.IsLoopback() {
 				continue
 			}
@@ -0,0 +1,31 @@
+package iphelper
+
+import (
+	"net"
+	"testing"
+)
+
+func TestPreferences(t *testing.T) {
+	defer func() {
+		_ = SetPreferences()
+	}()
+
+	if err := SetPreferences("10.0.0.0/33"); err == nil {
+		t.Fatal("invalid CIDR accepted")
+	}
+	if err := SetPreferences("203.0.113.0/24", "127.0.0.0/8"); err != nil {
+		t.Fatal(err)
+	}
+	if ip := LocalIP(); ip != "127.0.0.1" {
+		t.Fatalf("bad preferred ip: %v", ip)
+	}
+	if _, err := net.InterfaceByName("lo"); err != nil {
+		return
+	}
+	if err := SetPreferences("lo"); err != nil {
+		t.Fatal(err)
+	}
+	if ip := LocalIP(); ip != "127.0.0.1" && ip != "::1" {
+		t.Fatalf("bad preferred ip: %v", ip)
+	}
+}
@@ -0,0 +1,181 @@
+package jwk
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"math/big"
+)
+
+var (
+	ErrSymmetricKey   = errors.New("jwk: symmetric key can not be published")
+	ErrUnsupportedKey = errors.New("jwk: unsupported key")
+)
+
+// Key is a public JSON Web Key, RFC 7517.
+type Key struct {
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// Set is a JSON Web Key Set.
+type Set struct {
+	Keys []*Key `json:"keys"`
+}
+
+// Find returns the key of the specified ID, or nil if not found.
+func (s *Set) Find(kid string) *Key {
+	for _, k := range s.Keys {
+		if k.Kid == kid {
+			return k
+		}
+	}
+	return nil
+}
+
+// PublicKey returns the PKIX DER encoded public key of the asymmetric key,
+// which is a PKCS1 RSA or EC private key, or a PKIX public key.
+// ErrSymmetricKey is returned for other keys, raw Ed25519 private keys are not detected,
+// use Ed25519PublicKey instead.
+func PublicKey(key []byte) ([]byte, error) {
+	if priv, err := x509.ParsePKCS1PrivateKey(key); err == nil {
+		return x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	}
+	if priv, err := x509.ParseECPrivateKey(key); err == nil {
+		return x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	}
+	if _, err := x509.ParsePKIXPublicKey(key); err == nil {
+		return key, nil
+	}
+	return nil, ErrSymmetricKey
+}
+
+// Ed25519PublicKey returns the PKIX DER encoded public key of the raw Ed25519 private key,
+// or the PKIX public key itself.
+func Ed25519PublicKey(key []byte) ([]byte, error) {
+	if pub, err := x509.ParsePKIXPublicKey(key); err == nil {
+		if _, ok := pub.(ed25519.PublicKey); !ok {
+			return nil, ErrUnsupportedKey
+		}
+		return key, nil
+	}
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, ErrUnsupportedKey
+	}
+	return x509.MarshalPKIXPublicKey(ed25519.PrivateKey(key).Public())
+}
+
+// New returns the JSON Web Key of the PKIX DER encoded public key.
+func New(kid string, pkix []byte) (*Key, error) {
+	pub, err := x509.ParsePKIXPublicKey(pkix)
+	if err != nil {
+		return nil, err
+	}
+	k := &Key{
+		Use: "sig",
+		Kid: kid,
+	}
+	switch v := pub.(type) {
+	case *rsa.PublicKey:
+		k.Kty = "RSA"
+		k.N = encode(v.N.Bytes())
+		k.E = encode(big.NewInt(int64(v.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (v.Curve.Params().BitSize + 7) / 8
+		k.Kty = "EC"
+		k.Crv = v.Curve.Params().Name
+		k.X = encode(pad(v.X.Bytes(), size))
+		k.Y = encode(pad(v.Y.Bytes(), size))
+	case ed25519.PublicKey:
+		k.Kty = "OKP"
+		k.Crv = "Ed25519"
+		k.X = encode(v)
+	default:
+		return nil, ErrUnsupportedKey
+	}
+	return k, nil
+}
+
+// PKIX returns the PKIX DER encoded public key.
+func (k *Key) PKIX() ([]byte, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return x509.MarshalPKIXPublicKey(&rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		})
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, ErrUnsupportedKey
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return x509.MarshalPKIXPublicKey(&ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		})
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, ErrUnsupportedKey
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, ErrUnsupportedKey
+		}
+		return x509.MarshalPKIXPublicKey(ed25519.PublicKey(x))
+	default:
+		return nil, ErrUnsupportedKey
+	}
+}
+
+func pad(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	return append(make([]byte, size-len(b)), b...)
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
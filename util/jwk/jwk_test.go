@@ -0,0 +1,58 @@
+package jwk
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	ecDER, _ := x509.MarshalECPrivateKey(ecKey)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	for name, priv := range map[string][]byte{
+		"RSA":     x509.MarshalPKCS1PrivateKey(rsaKey),
+		"EC":      ecDER,
+		"Ed25519": edKey,
+	} {
+		pub, err := PublicKey(priv)
+		if name == "Ed25519" {
+			if err != ErrSymmetricKey {
+				t.Fatal(name, "raw private key detected")
+			}
+			pub, err = Ed25519PublicKey(priv)
+		}
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if again, err := PublicKey(pub); err != nil || !bytes.Equal(again, pub) {
+			t.Fatal(name, "public key not kept")
+		}
+		k, err := New("1", pub)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		der, err := k.PKIX()
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if !bytes.Equal(der, pub) {
+			t.Fatal(name, "round trip mismatch")
+		}
+	}
+
+	if _, err := PublicKey([]byte("1d011a3a57f9d3fa38541713ae03c6a238233bd2")); err != ErrSymmetricKey {
+		t.Fatal("symmetric key published")
+	}
+	// 64 bytes HMAC secret is not an Ed25519 key.
+	if _, err := PublicKey([]byte("1d011a3a57f9d3fa38541713ae03c6a238233bd21d011a3a57f9d3fa38541713")); err != ErrSymmetricKey {
+		t.Fatal("symmetric key published")
+	}
+}
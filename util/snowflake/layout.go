@@ -0,0 +1,56 @@
+package snowflake
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidLayout = errors.New("snowflake: invalid bit layout")
+)
+
+// Layout of the snowflake ID bits.
+type Layout struct {
+	TimestampBits uint
+	PartitionBits uint
+	SequenceBits  uint
+	// TimeUnit is the resolution of the timestamp, millisecond or second for example.
+	TimeUnit time.Duration
+}
+
+// DefaultLayout is the 41/10/12 bits layout in millisecond.
+var DefaultLayout = Layout{
+	TimestampBits: BitLengthTimestamp,
+	PartitionBits: BitLengthPartitionID,
+	SequenceBits:  BitLengthSequence,
+	TimeUnit:      time.Millisecond,
+}
+
+// Validate the layout, the sum of the bits should be 63
+// and the partition bits should not exceed 15.
+func (l Layout) Validate() error {
+	if l.TimestampBits+l.PartitionBits+l.SequenceBits != 63 ||
+		l.TimestampBits == 0 || l.PartitionBits > 15 || l.TimeUnit <= 0 {
+		return ErrInvalidLayout
+	}
+	return nil
+}
+
+// TimestampMask returns the max value of the timestamp.
+func (l Layout) TimestampMask() int64 {
+	return 1<<l.TimestampBits - 1
+}
+
+// PartitionMask returns the max value of the partition ID.
+func (l Layout) PartitionMask() int64 {
+	return 1<<l.PartitionBits - 1
+}
+
+// SequenceMask returns the max value of the sequence.
+func (l Layout) SequenceMask() int64 {
+	return 1<<l.SequenceBits - 1
+}
+
+func (l Layout) timestampShift() uint {
+	return l.PartitionBits + l.SequenceBits
+}
@@ -4,6 +4,8 @@ import (
 	"time"
 )
 
+// Sequence returns the elapsed time since epoch and the sequence number,
+// the elapsed time should be in the TimeUnit of the snowflake layout.
 type Sequence interface {
 	Next(partition int16, epoch time.Time) (int64, int64, error)
 }
@@ -16,9 +18,23 @@ func WithEpoch(epoch time.Time) Option {
 	}
 }
 
+// WithLayout sets the bit layout, the timestamp resolution is specified by the TimeUnit.
+func WithLayout(layout Layout) Option {
+	return func(snowflake *Snowflake) {
+		snowflake.layout = layout
+	}
+}
+
+// WithPartitionID sets the partition ID, masked by the partition bits of the layout.
 func WithPartitionID(partition int64) Option {
 	return func(snowflake *Snowflake) {
-		snowflake.partition = int16(partition % PartitionIDBitMask)
+		snowflake.partition = int16(partition)
+	}
+}
+
+func WithLease(lease Lease) Option {
+	return func(snowflake *Snowflake) {
+		snowflake.lease = lease
 	}
 }
 
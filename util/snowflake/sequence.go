@@ -5,9 +5,19 @@ import (
 	"time"
 )
 
+const (
+	DefaultMaxBackwards = 10 * time.Millisecond
+)
+
 type DefaultSequence struct {
 	mu sync.Mutex
 
+	// MaxBackwards is the max clock regression to wait for,
+	// ErrClockBackwards is returned if exceeded. DefaultMaxBackwards is used if not set.
+	MaxBackwards time.Duration
+
+	unit     time.Duration
+	mask     int64
 	elapsed  int64
 	sequence int64
 }
@@ -16,18 +26,44 @@ func (s *DefaultSequence) Next(_ int16, epoch time.Time) (int64, int64, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	elapsed := time.Since(epoch).Nanoseconds() / 1e6
+	unit, mask := s.layout()
+	since := time.Since(epoch)
+	elapsed := int64(since / unit)
+	if elapsed < s.elapsed {
+		// Clock moved backwards, wait for catching up.
+		backwards := time.Duration(s.elapsed)*unit - since
+		if backwards > s.maxBackwards() {
+			return 0, 0, ErrClockBackwards
+		}
+		time.Sleep(backwards)
+		elapsed = s.elapsed
+	}
+
 	if s.elapsed < elapsed {
 		s.elapsed = elapsed
 		s.sequence = 0
 	} else {
-		s.sequence = (s.sequence + 1) & SequenceBitMask
+		s.sequence = (s.sequence + 1) & mask
 		if s.sequence == 0 {
 			s.elapsed++
 			overtime := time.Duration(s.elapsed - elapsed)
-			time.Sleep(overtime * time.Millisecond)
+			time.Sleep(overtime * unit)
 		}
 	}
 
 	return s.elapsed, s.sequence, nil
 }
+
+func (s *DefaultSequence) layout() (time.Duration, int64) {
+	if s.unit > 0 {
+		return s.unit, s.mask
+	}
+	return DefaultLayout.TimeUnit, DefaultLayout.SequenceMask()
+}
+
+func (s *DefaultSequence) maxBackwards() time.Duration {
+	if s.MaxBackwards > 0 {
+		return s.MaxBackwards
+	}
+	return DefaultMaxBackwards
+}
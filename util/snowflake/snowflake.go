@@ -1,11 +1,13 @@
 package snowflake
 
 import (
+	"errors"
 	"sync"
 	"time"
 )
 
 //
+// Default layout:
 // +-------------------------------------------------------------------------------+
 // | 1 Bit Unused | 41 Bit Timestamp |  10 Bit PartitionID  |   12 Bit Sequence ID |
 // +-------------------------------------------------------------------------------+
@@ -21,12 +23,22 @@ const (
 	SequenceBitMask    = 1<<BitLengthSequence - 1
 )
 
+// DefaultEpoch is 2020-02-02 20:20:02 UTC+14, the earliest of the former host local epochs
+// 2020-02-02 20:20:02 in any time zone, so new IDs are always greater than the IDs generated before.
+// Timestamps of the former IDs decode earlier by the offset between the host time zone and UTC+14,
+// use WithEpoch(time.Date(2020, 2, 2, 20, 20, 2, 2, time.Local)) to keep the former epoch of the host.
+var DefaultEpoch = time.Date(2020, 2, 2, 20, 20, 2, 0, time.FixedZone("UTC+14", 14*60*60)).UTC()
+
+var (
+	ErrPartitionLost     = errors.New("snowflake: partition lease lost")
+	ErrClockBackwards    = errors.New("snowflake: clock moved backwards")
+	ErrTimestampOverflow = errors.New("snowflake: timestamp overflow")
+)
+
 var Default = New()
 
 func SetPartitionID(partitionID int64) {
-	Default.mu.Lock()
-	Default.partition = int16(partitionID % PartitionIDBitMask)
-	Default.mu.Unlock()
+	Default.SetPartitionID(partitionID)
 }
 
 func NextID() (uint64, error) {
@@ -38,33 +50,78 @@ func Timestamp(id uint64) time.Time {
 }
 
 func PartitionID(id uint64) uint64 {
-	return (id >> BitLengthSequence) & PartitionIDBitMask
+	return Default.PartitionID(id)
+}
+
+func Decode(id uint64) (time.Time, int64, int64) {
+	return Default.Decode(id)
+}
+
+// Lease reports whether the partition ID is still held by the snowflake.
+type Lease interface {
+	Valid() bool
 }
 
 type Snowflake struct {
 	epoch    time.Time
+	layout   Layout
 	sequence Sequence
 
 	mu        sync.RWMutex
 	partition int16
+	lease     Lease
 }
 
+// New creates a snowflake, panics if the layout is invalid.
 func New(opts ...Option) *Snowflake {
 	snowflake := &Snowflake{
-		epoch:    time.Date(2020, 02, 02, 20, 20, 02, 02, time.Local),
+		epoch:    DefaultEpoch,
+		layout:   DefaultLayout,
 		sequence: &DefaultSequence{},
 	}
 	for _, opt := range opts {
 		opt(snowflake)
 	}
+	if err := snowflake.layout.Validate(); err != nil {
+		panic(err)
+	}
+	snowflake.partition &= int16(snowflake.layout.PartitionMask())
+	if seq, ok := snowflake.sequence.(*DefaultSequence); ok {
+		seq.unit = snowflake.layout.TimeUnit
+		seq.mask = snowflake.layout.SequenceMask()
+	}
 	return snowflake
 }
 
+// Layout returns the bit layout.
+func (sf *Snowflake) Layout() Layout {
+	return sf.layout
+}
+
+// SetPartitionID updates the partition ID.
+func (sf *Snowflake) SetPartitionID(partitionID int64) {
+	sf.mu.Lock()
+	sf.partition = int16(partitionID & sf.layout.PartitionMask())
+	sf.mu.Unlock()
+}
+
+// SetLease sets the lease of the partition ID,
+// no ID will be generated if the lease is invalid.
+func (sf *Snowflake) SetLease(lease Lease) {
+	sf.mu.Lock()
+	sf.lease = lease
+	sf.mu.Unlock()
+}
+
 func (sf *Snowflake) Next() (uint64, error) {
 	sf.mu.RLock()
 	partition := sf.partition
+	lease := sf.lease
 	sf.mu.RUnlock()
 
+	if lease != nil && !lease.Valid() {
+		return 0, ErrPartitionLost
+	}
 	return sf.CustomNext(partition, sf.sequence)
 }
 
@@ -73,14 +130,30 @@ func (sf *Snowflake) CustomNext(partition int16, sequence Sequence) (uint64, err
 	if err != nil {
 		return 0, err
 	}
+	if elapsed > sf.layout.TimestampMask() {
+		return 0, ErrTimestampOverflow
+	}
 	//
-	num &= SequenceBitMask
-	return uint64(elapsed)<<TimestampBitShift |
-		uint64(partition)<<PartitionIDBitShift |
+	num &= sf.layout.SequenceMask()
+	return uint64(elapsed)<<sf.layout.timestampShift() |
+		uint64(int64(partition)&sf.layout.PartitionMask())<<sf.layout.SequenceBits |
 		uint64(num), nil
 }
 
 func (sf *Snowflake) Timestamp(id uint64) time.Time {
-	dur := id >> (BitLengthPartitionID + BitLengthSequence)
-	return sf.epoch.Add(time.Duration(dur) * time.Millisecond)
+	elapsed := id >> sf.layout.timestampShift()
+	return sf.epoch.Add(time.Duration(elapsed) * sf.layout.TimeUnit)
+}
+
+func (sf *Snowflake) PartitionID(id uint64) uint64 {
+	return (id >> sf.layout.SequenceBits) & uint64(sf.layout.PartitionMask())
+}
+
+func (sf *Snowflake) Sequence(id uint64) uint64 {
+	return id & uint64(sf.layout.SequenceMask())
+}
+
+// Decode returns the timestamp, partition ID and sequence of the ID.
+func (sf *Snowflake) Decode(id uint64) (time.Time, int64, int64) {
+	return sf.Timestamp(id), int64(sf.PartitionID(id)), int64(sf.Sequence(id))
 }
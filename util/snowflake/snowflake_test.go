@@ -32,3 +32,96 @@ func TestSnowflake_Timestamp(t *testing.T) {
 	}
 	t.Log("diff", diff)
 }
+
+func TestDefaultEpoch(t *testing.T) {
+	// Former epochs were 2020-02-02 20:20:02 of the host local time zone, from UTC-12 to UTC+14.
+	for offset := -12; offset <= 14; offset++ {
+		former := time.Date(2020, 2, 2, 20, 20, 2, 2, time.FixedZone("", offset*60*60))
+		if DefaultEpoch.After(former) {
+			t.Fatal("default epoch after the former epoch of UTC", offset)
+		}
+	}
+}
+
+type lease bool
+
+func (l lease) Valid() bool {
+	return bool(l)
+}
+
+func TestSnowflake_Lease(t *testing.T) {
+	sf := New(WithPartitionID(PartitionIDBitMask), WithLease(lease(true)))
+	id, err := sf.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if PartitionID(id) != PartitionIDBitMask {
+		t.Fatal("unexpected partition", PartitionID(id))
+	}
+
+	sf.SetLease(lease(false))
+	if _, err = sf.Next(); err != ErrPartitionLost {
+		t.Fatal("expected lease lost, got", err)
+	}
+}
+
+func TestDefaultSequence_Backwards(t *testing.T) {
+	seq := &DefaultSequence{}
+	epoch := time.Now().Add(-time.Hour)
+	elapsed, _, err := seq.Next(0, epoch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Small regression, wait for catching up.
+	next, _, err := seq.Next(0, epoch.Add(DefaultMaxBackwards/2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next < elapsed {
+		t.Fatal("timestamp moved backwards", elapsed, next)
+	}
+
+	// Large regression.
+	if _, _, err = seq.Next(0, epoch.Add(time.Second)); err != ErrClockBackwards {
+		t.Fatal("expected clock backwards, got", err)
+	}
+}
+
+func TestSnowflake_Layout(t *testing.T) {
+	sf := New(WithPartitionID(5), WithLayout(Layout{
+		TimestampBits: 35,
+		PartitionBits: 6,
+		SequenceBits:  22,
+		TimeUnit:      time.Second,
+	}))
+	id, err := sf.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts, partition, sequence := sf.Decode(id)
+	if partition != 5 || sequence != 0 {
+		t.Fatal("unexpected decode", partition, sequence)
+	}
+	if diff := time.Since(ts); diff < 0 || diff > time.Second {
+		t.Fatal("unexpected timestamp", ts)
+	}
+	next, _ := sf.Next()
+	if nts, _, sequence := sf.Decode(next); nts.Equal(ts) && sequence != 1 {
+		t.Fatal("unexpected sequence", sequence)
+	}
+}
+
+func TestSnowflake_InvalidLayout(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+	New(WithLayout(Layout{
+		TimestampBits: 41,
+		PartitionBits: 10,
+		SequenceBits:  10,
+		TimeUnit:      time.Millisecond,
+	}))
+}
@@ -0,0 +1,136 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"time"
+)
+
+const (
+	DefaultReloadInterval = 10 * time.Second
+)
+
+var (
+	ErrInvalidCA     = errors.New("tlsconfig: no valid certificate in CA file")
+	ErrNoCertificate = errors.New("tlsconfig: no peer certificate")
+)
+
+// Options of the TLS configuration.
+type Options struct {
+	// CertFile and KeyFile of the PEM encoded key pair.
+	CertFile string
+	KeyFile  string
+	// CAFile of the PEM encoded certificates.
+	// Server side: verifies the client certificates (mTLS) if set.
+	// Client side: verifies the server certificates, system roots are used if not set.
+	CAFile string
+	// ServerName of the client side, used to verify the hostname of the server certificates.
+	// The CA file of the client side is reloaded on change only if set, since the hostname of
+	// the dialing target is not known when verifying by the reloaded CA.
+	ServerName string
+	// ReloadInterval for checking the key pair and CA files changes.
+	ReloadInterval time.Duration
+}
+
+func (o *Options) reloadInterval() time.Duration {
+	if o.ReloadInterval > 0 {
+		return o.ReloadInterval
+	}
+	return DefaultReloadInterval
+}
+
+// NewServerConfig returns the server side TLS configuration,
+// the key pair and client CA are reloaded on change.
+func NewServerConfig(opts Options) (*tls.Config, error) {
+	keyPair, err := newKeyPair(opts.CertFile, opts.KeyFile, opts.reloadInterval())
+	if err != nil {
+		return nil, err
+	}
+	var clientCA *certPool
+	if opts.CAFile != "" {
+		if clientCA, err = newCertPool(opts.CAFile, opts.reloadInterval()); err != nil {
+			return nil, err
+		}
+	}
+	base := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+	cfg := base.Clone()
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		c := base.Clone()
+		c.GetCertificate = keyPair.GetCertificate
+		if clientCA != nil {
+			c.ClientAuth = tls.RequireAndVerifyClientCert
+			c.ClientCAs = clientCA.Get()
+		}
+		return c, nil
+	}
+	cfg.GetCertificate = keyPair.GetCertificate
+	return cfg, nil
+}
+
+// NewClientConfig returns the client side TLS configuration,
+// the client key pair is optional and reloaded on change if set,
+// the CA file is reloaded on change if the server name is set.
+func NewClientConfig(opts Options) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: opts.ServerName,
+	}
+	if opts.CAFile != "" && opts.ServerName != "" {
+		rootCA, err := newCertPool(opts.CAFile, opts.reloadInterval())
+		if err != nil {
+			return nil, err
+		}
+		// Verified by the reloaded CA instead of the RootCAs loaded once.
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyCertificate(rawCerts, rootCA.Get(), opts.ServerName)
+		}
+	} else if opts.CAFile != "" {
+		pem, err := ioutil.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, ErrInvalidCA
+		}
+	}
+	if opts.CertFile != "" {
+		keyPair, err := newKeyPair(opts.CertFile, opts.KeyFile, opts.reloadInterval())
+		if err != nil {
+			return nil, err
+		}
+		cfg.GetClientCertificate = keyPair.GetClientCertificate
+	}
+	return cfg, nil
+}
+
+// verifyCertificate verifies the peer certificate chain and the hostname by the roots.
+func verifyCertificate(rawCerts [][]byte, roots *x509.CertPool, serverName string) error {
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return ErrNoCertificate
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		DNSName:       serverName,
+	})
+	return err
+}
@@ -0,0 +1,179 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeKeyPair(t *testing.T, dir, name string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	_ = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	_ = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	return certFile, keyFile
+}
+
+func handshake(serverCfg, clientCfg *tls.Config) error {
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", serverCfg)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+
+	errChan := make(chan error, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			errChan <- err
+			return
+		}
+		defer conn.Close()
+		if err = conn.(*tls.Conn).Handshake(); err != nil {
+			errChan <- err
+			return
+		}
+		_, err = conn.Write([]byte{1})
+		errChan <- err
+	}()
+
+	conn, err := tls.Dial("tcp", ln.Addr().String(), clientCfg)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// Client certificates are verified after the client handshake in TLS 1.3.
+	if _, err = conn.Read(make([]byte, 1)); err != nil {
+		return err
+	}
+	return <-errChan
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	serverCert, serverKey := writeKeyPair(t, dir, "server")
+	clientCert, clientKey := writeKeyPair(t, dir, "client")
+
+	serverCfg, err := NewServerConfig(Options{
+		CertFile: serverCert,
+		KeyFile:  serverKey,
+		CAFile:   clientCert,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCfg, err := NewClientConfig(Options{
+		CertFile:   clientCert,
+		KeyFile:    clientKey,
+		CAFile:     serverCert,
+		ServerName: "server",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = handshake(serverCfg, clientCfg); err != nil {
+		t.Fatal(err)
+	}
+
+	// Client certificate required.
+	clientCfg, _ = NewClientConfig(Options{
+		CAFile:     serverCert,
+		ServerName: "server",
+	})
+	if err = handshake(serverCfg, clientCfg); err == nil {
+		t.Fatal("expected client certificate required")
+	}
+}
+
+func TestClientCAReload(t *testing.T) {
+	dir := t.TempDir()
+	oldCert, oldKey := writeKeyPair(t, t.TempDir(), "server")
+	newCert, newKey := writeKeyPair(t, t.TempDir(), "server")
+	caFile := filepath.Join(dir, "ca.crt")
+	writeCA := func(certFile string) {
+		buf, _ := ioutil.ReadFile(certFile)
+		if err := ioutil.WriteFile(caFile, buf, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeCA(oldCert)
+
+	oldCfg, err := NewServerConfig(Options{CertFile: oldCert, KeyFile: oldKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newCfg, err := NewServerConfig(Options{CertFile: newCert, KeyFile: newKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCfg, err := NewClientConfig(Options{
+		CAFile:         caFile,
+		ServerName:     "server",
+		ReloadInterval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = handshake(oldCfg, clientCfg); err != nil {
+		t.Fatal(err)
+	}
+	if err = handshake(newCfg, clientCfg); err == nil {
+		t.Fatal("expected unknown authority")
+	}
+
+	// Server certificate rotated with the CA file.
+	writeCA(newCert)
+	modTime := time.Now().Add(time.Second)
+	if err = os.Chtimes(caFile, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if err = handshake(newCfg, clientCfg); err != nil {
+		t.Fatal(err)
+	}
+	if err = handshake(oldCfg, clientCfg); err == nil {
+		t.Fatal("expected unknown authority after reloading")
+	}
+
+	// Hostname verified.
+	clientCfg, _ = NewClientConfig(Options{
+		CAFile:     caFile,
+		ServerName: "other",
+	})
+	if err = handshake(newCfg, clientCfg); err == nil {
+		t.Fatal("expected hostname mismatch")
+	}
+}
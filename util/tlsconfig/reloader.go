@@ -0,0 +1,139 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// fileWatcher reports whether the files changed, checked at most once per interval.
+type fileWatcher struct {
+	files    []string
+	interval time.Duration
+
+	checked time.Time
+	modTime time.Time
+}
+
+func (w *fileWatcher) changed() bool {
+	now := time.Now()
+	if now.Sub(w.checked) < w.interval {
+		return false
+	}
+	w.checked = now
+	latest := time.Time{}
+	for _, file := range w.files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return false
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	if latest.Equal(w.modTime) {
+		return false
+	}
+	w.modTime = latest
+	return true
+}
+
+type keyPair struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.Mutex
+	watcher *fileWatcher
+	cert    *tls.Certificate
+}
+
+func newKeyPair(certFile, keyFile string, interval time.Duration) (*keyPair, error) {
+	kp := &keyPair{
+		certFile: certFile,
+		keyFile:  keyFile,
+		watcher: &fileWatcher{
+			files:    []string{certFile, keyFile},
+			interval: interval,
+		},
+	}
+	kp.watcher.changed()
+	if err := kp.load(); err != nil {
+		return nil, err
+	}
+	return kp, nil
+}
+
+func (kp *keyPair) load() error {
+	cert, err := tls.LoadX509KeyPair(kp.certFile, kp.keyFile)
+	if err != nil {
+		return err
+	}
+	kp.cert = &cert
+	return nil
+}
+
+func (kp *keyPair) get() (*tls.Certificate, error) {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	if kp.watcher.changed() {
+		// Keep the previous key pair if the files are being written.
+		_ = kp.load()
+	}
+	return kp.cert, nil
+}
+
+func (kp *keyPair) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return kp.get()
+}
+
+func (kp *keyPair) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return kp.get()
+}
+
+type certPool struct {
+	file string
+
+	mu      sync.Mutex
+	watcher *fileWatcher
+	pool    *x509.CertPool
+}
+
+func newCertPool(file string, interval time.Duration) (*certPool, error) {
+	cp := &certPool{
+		file: file,
+		watcher: &fileWatcher{
+			files:    []string{file},
+			interval: interval,
+		},
+	}
+	cp.watcher.changed()
+	if err := cp.load(); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+func (cp *certPool) load() error {
+	pem, err := ioutil.ReadFile(cp.file)
+	if err != nil {
+		return err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return ErrInvalidCA
+	}
+	cp.pool = pool
+	return nil
+}
+
+func (cp *certPool) Get() *x509.CertPool {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if cp.watcher.changed() {
+		_ = cp.load()
+	}
+	return cp.pool
+}